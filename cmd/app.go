@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/DSiSc/justitia/version"
+	"github.com/urfave/cli"
+	"sort"
+)
+
+// NewApp return the justitia command line application, which starts a node if no command is given.
+func NewApp() *cli.App {
+	app := cli.NewApp()
+	app.Name = "justitia"
+	app.Usage = "the justitia block chain node"
+	app.Version = fullVersion()
+	app.HideVersion = true
+	app.Flags = globalFlags
+	app.Action = action(startNode)
+	app.Commands = []cli.Command{
+		InitCommand,
+		StartCommand,
+		VersionCommand,
+		GenesisCommand,
+		ConfigCommand,
+		ExportCommand,
+		ImportCommand,
+		DevnetCommand,
+	}
+	sort.Sort(cli.CommandsByName(app.Commands))
+	return app
+}
+
+// fullVersion return version with pre-release marker.
+func fullVersion() string {
+	if "" == version.VersionPrerelease {
+		return version.Version
+	}
+	return fmt.Sprintf("%s-%s", version.Version, version.VersionPrerelease)
+}
+
+var VersionCommand = cli.Command{
+	Name:  "version",
+	Usage: "Print version, git commit and build date",
+	Action: func(ctx *cli.Context) error {
+		fmt.Fprintf(ctx.App.Writer, "Version:    %s\n", fullVersion())
+		fmt.Fprintf(ctx.App.Writer, "Git commit: %s\n", version.GitCommit)
+		fmt.Fprintf(ctx.App.Writer, "Build date: %s\n", version.BuildDate)
+		return nil
+	},
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/DSiSc/repository"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runApp(args ...string) (string, error) {
+	app := NewApp()
+	output := new(bytes.Buffer)
+	app.Writer = output
+	err := app.Run(append([]string{"justitia"}, args...))
+	return output.String(), err
+}
+
+func TestVersionCommand(t *testing.T) {
+	assert := assert.New(t)
+	output, err := runApp("version")
+	assert.Nil(err)
+	assert.Contains(output, fullVersion())
+	assert.Contains(output, "Git commit")
+}
+
+func TestInitCommand(t *testing.T) {
+	assert := assert.New(t)
+	home, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(home)
+	defer config.SetHome("")
+
+	_, err = runApp("--home", home, "init")
+	assert.Nil(err)
+	content, err := ioutil.ReadFile(filepath.Join(home, config.ConfigDirName, config.GenesisFileName))
+	assert.Nil(err)
+	assert.Equal(config.DefaultGenesisFile, content)
+	for _, dir := range []string{config.DataDirName, config.P2PDirName, config.LogDirName} {
+		assert.True(tools.PathExists(filepath.Join(home, dir)))
+	}
+	// home given after command name
+	_, err = runApp("init", "--home", home)
+	assert.NotNil(err)
+	_, err = runApp("init", "--home", home, "--force")
+	assert.Nil(err)
+
+	output, err := runApp("config", "validate", "--home", home)
+	assert.Nil(err)
+	assert.Contains(output, "valid")
+	output, err = runApp("--home", home, "genesis", "show")
+	assert.Nil(err)
+	assert.Contains(output, "Chain id: 0")
+	assert.Contains(output, "Time:     2018-08-28T00:00:00Z")
+	assert.Contains(output, "Voting")
+	assert.Contains(output, "JustitiaRight 0x"+types.JustiitaContractDefaultAddress)
+
+	invalid := filepath.Join(home, "invalid.yaml")
+	content = bytes.Replace(config.DefaultConfigFile, []byte("policy: solo\n    enableEmptyBlock"), []byte("policy: pow\n    enableEmptyBlock"), 1)
+	content = bytes.Replace(content, []byte("port: 6060"), []byte("port: 6666"), 1)
+	assert.Nil(ioutil.WriteFile(invalid, content, 0644))
+	defer config.SetConfigFile("")
+	output, err = runApp("config", "validate", "--home", home, "--config", invalid)
+	assert.NotNil(err)
+	assert.Contains(output, config.ConsensusPolicy)
+	assert.Contains(output, config.PprofPort)
+}
+
+func TestExportImportCommand(t *testing.T) {
+	assert := assert.New(t)
+	home, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(home)
+	defer config.SetHome("")
+	defer config.SetConfigFile("")
+	_, err = runApp("--home", home, "init")
+	assert.Nil(err)
+
+	// memorydb keeps no block to export
+	_, err = runApp("--home", home, "export", filepath.Join(home, "blocks.json"))
+	assert.NotNil(err)
+
+	// repository could not be closed, so blocks are imported into another one.
+	for _, name := range []string{"export", "import"} {
+		nodeConfig := viper.New()
+		nodeConfig.SetConfigFile(filepath.Join(home, config.ConfigDirName, "justitia.yaml"))
+		assert.Nil(nodeConfig.ReadInConfig())
+		nodeConfig.Set(config.RepositoryPlugin, repository.PLUGIN_LEVELDB)
+		nodeConfig.Set(config.RepositoryStatePath, filepath.Join(home, name, "state"))
+		nodeConfig.Set(config.RepositoryDataPath, filepath.Join(home, name, "block"))
+		assert.Nil(nodeConfig.WriteConfigAs(filepath.Join(home, name+".yaml")))
+	}
+
+	blocksFile := filepath.Join(home, "blocks.json")
+	_, err = runApp("--home", home, "--config", filepath.Join(home, "export.yaml"), "export", blocksFile)
+	assert.Nil(err)
+	blocks, err := ioutil.ReadFile(blocksFile)
+	assert.Nil(err)
+	assert.Equal(1, strings.Count(string(blocks), "\n"))
+
+	output, err := runApp("--home", home, "--config", filepath.Join(home, "import.yaml"), "import", blocksFile)
+	assert.Nil(err)
+	assert.Contains(output, "Imported 0 blocks, skipped 1 existing blocks.")
+
+	// genesis block of another chain
+	block := new(types.Block)
+	assert.Nil(json.Unmarshal(blocks, block))
+	block.HeaderHash = types.Hash{0x1}
+	forked, err := json.Marshal(block)
+	assert.Nil(err)
+	_, _, err = importBlocks(bytes.NewReader(forked), nil)
+	assert.NotNil(err)
+}
+
+func TestConfigShowCommand(t *testing.T) {
+	assert := assert.New(t)
+	home, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(home)
+	defer config.SetHome("")
+	defer config.SetConfigFile("")
+	_, err = runApp("--home", home, "init")
+	assert.Nil(err)
+
+	file := filepath.Join(home, "show.yaml")
+	assert.Nil(ioutil.WriteFile(file, config.DefaultConfigFile, 0644))
+	os.Setenv("JUSTITIA_GENERAL_TXPOOL_GLOBALSLOTS", "2048")
+	defer os.Unsetenv("JUSTITIA_GENERAL_TXPOOL_GLOBALSLOTS")
+
+	output, err := runApp("config", "show", "--home", home, "--config", file, "--log_level", "2")
+	assert.Nil(err)
+	sources := make(map[string][]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 1 {
+			sources[fields[0]] = fields[1:]
+		}
+	}
+	assert.Equal([]string{"2048", config.SourceEnv}, sources[config.TxpoolSlots])
+	assert.Equal([]string{"2", config.SourceFlag}, sources[config.LogFileLevel])
+	assert.Equal(config.SourceFile, sources[config.NodeType][1])
+}
+
+func TestGenesisNewCommand(t *testing.T) {
+	assert := assert.New(t)
+	home, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(home)
+	defer config.SetHome("")
+	defer config.SetConfigFile("")
+	_, err = runApp("--home", home, "init")
+	assert.Nil(err)
+
+	args := []string{"genesis", "new", "--home", home, "--chain-id", "7",
+		"--account", "0xA94F5374FCE5EDBC8E2A8697C15331677E6EBF0B=50000",
+		"--participate", "333c3310824b7c685133f2bedb2ca4b8b4df633d@127.0.0.1:8080",
+		"--contract", "MetaData", "--contract", "WhiteList", "--prebuilt"}
+	_, err = runApp(args...)
+	assert.NotNil(err)
+	output, err := runApp(append(args, "--force")...)
+	assert.Nil(err)
+	hash := strings.TrimSpace(output[strings.Index(output, "Hash:")+len("Hash:"):])
+
+	content, err := ioutil.ReadFile(filepath.Join(home, config.ConfigDirName, config.GenesisFileName))
+	assert.Nil(err)
+	var genesis config.GenesisBlockConfig
+	assert.Nil(json.Unmarshal(content, &genesis))
+	assert.Equal(uint64(7), genesis.Block.Header.ChainID)
+	assert.Equal(3, len(genesis.GenesisAccounts))
+	assert.Equal("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", genesis.GenesisAccounts[0].Addr)
+	// contracts are deployed in canonical order
+	assert.Equal("WhiteList", genesis.GenesisAccounts[1].Contract)
+	assert.Equal("MetaData", genesis.GenesisAccounts[2].Contract)
+	assert.NotEmpty(genesis.GenesisAccounts[2].Code)
+	assert.Equal("0x333c3310824b7c685133f2bedb2ca4b8b4df633d", genesis.Participates[0].Addr)
+
+	output, err = runApp("--home", home, "genesis", "show")
+	assert.Nil(err)
+	assert.Contains(output, "Hash:     "+hash)
+	assert.Contains(output, "Chain id: 7")
+
+	_, err = runApp("genesis", "new", "--home", home, "--force", "--contract", "Unknown")
+	assert.NotNil(err)
+	_, err = runApp("genesis", "new", "--home", home, "--force", "--contract", "CrossFundsPool", "--prebuilt")
+	assert.NotNil(err)
+	// contracts are compiled by default, and WhiteList has no source to compile
+	_, err = runApp("genesis", "new", "--home", home, "--force", "--contract", "WhiteList")
+	assert.NotNil(err)
+	_, err = runApp("genesis", "new", "--home", home, "--force", "--account", "0x01=10")
+	assert.NotNil(err)
+}
+
+func TestGenesisExportCommand(t *testing.T) {
+	assert := assert.New(t)
+	home, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(home)
+	defer config.SetHome("")
+	defer config.SetConfigFile("")
+	_, err = runApp("--home", home, "init")
+	assert.Nil(err)
+
+	_, err = runApp("genesis", "export", "--home", home)
+	assert.NotNil(err)
+	// default repository keeps no state
+	_, err = runApp("genesis", "export", "--home", home, filepath.Join(home, "exported.json"))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "memorydb")
+	_, err = runApp("genesis", "export", "--home", home, filepath.Join(home, "config", config.GenesisFileName))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "already exists")
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	blockFilter "github.com/DSiSc/gossipswitch/filter/block"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/repository"
+	"github.com/urfave/cli"
+	"io"
+	"math"
+	"os"
+)
+
+var (
+	fromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "Height of the first block to export",
+	}
+	toFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Height of the last block to export, default to current height",
+		Value: math.MaxUint64,
+	}
+)
+
+var ExportCommand = cli.Command{
+	Name:      "export",
+	Usage:     "Export blocks into file, one json encoded block per line",
+	ArgsUsage: "<file>",
+	Description: `Blocks are read from the repository in node config, so node should be stopped first.
+Use - as file to write to stdout.`,
+	Flags:  withGlobalFlags(fromFlag, toFlag),
+	Action: action(exportChain),
+}
+
+var ImportCommand = cli.Command{
+	Name:      "import",
+	Usage:     "Verify and import blocks exported by export command",
+	ArgsUsage: "<file>",
+	Description: `Blocks are executed and written to the repository in node config, so node should be stopped first.
+Blocks no higher than current block are skipped, after checking they are the same as local ones.`,
+	Flags:  withGlobalFlags(),
+	Action: action(importChain),
+}
+
+// openRepository initialize repository from node config, and import genesis block if chain is empty, or check
+// the chain is the one of genesis file otherwise.
+func openRepository(conf config.NodeConfig) error {
+	if repository.PLUGIN_MEMDB == conf.RepositoryConf.PluginName {
+		return fmt.Errorf("repository plugin %s keeps no block after node stopped", repository.PLUGIN_MEMDB)
+	}
+	if err := repository.InitRepository(conf.RepositoryConf, events.NewEvent()); nil != err {
+		return fmt.Errorf("init repository failed: %v", err)
+	}
+	return config.ImportGenesisBlock()
+}
+
+func exportChain(ctx *cli.Context) error {
+	if 1 != ctx.NArg() {
+		return fmt.Errorf("export needs exactly one file argument")
+	}
+	conf, err := loadNodeConfig()
+	if nil != err {
+		return err
+	}
+	if err := openRepository(conf); nil != err {
+		return err
+	}
+	writer := io.Writer(os.Stdout)
+	if file := ctx.Args().First(); "-" != file {
+		output, err := os.Create(file)
+		if nil != err {
+			return fmt.Errorf("create %s failed: %v", file, err)
+		}
+		defer output.Close()
+		writer = output
+	}
+	chain, err := repository.NewLatestStateRepository()
+	if nil != err {
+		return fmt.Errorf("open repository failed: %v", err)
+	}
+	exported, err := exportBlocks(chain, writer, ctx.Uint64(fromFlag.Name), ctx.Uint64(toFlag.Name))
+	log.Info("Exported %d blocks.", exported)
+	return err
+}
+
+// exportBlocks write blocks from height from to to, which is capped at current height.
+func exportBlocks(chain *repository.Repository, writer io.Writer, from uint64, to uint64) (int, error) {
+	if current := chain.GetCurrentBlockHeight(); to > current {
+		to = current
+	}
+	encoder := json.NewEncoder(writer)
+	exported := 0
+	for height := from; height <= to; height++ {
+		block, err := chain.GetBlockByHeight(height)
+		if nil != err {
+			return exported, fmt.Errorf("get block %d failed: %v", height, err)
+		}
+		if err := encoder.Encode(block); nil != err {
+			return exported, fmt.Errorf("write block %d failed: %v", height, err)
+		}
+		exported++
+	}
+	return exported, nil
+}
+
+func importChain(ctx *cli.Context) error {
+	if 1 != ctx.NArg() {
+		return fmt.Errorf("import needs exactly one file argument")
+	}
+	conf, err := loadNodeConfig()
+	if nil != err {
+		return err
+	}
+	if err := openRepository(conf); nil != err {
+		return err
+	}
+	input, err := os.Open(ctx.Args().First())
+	if nil != err {
+		return fmt.Errorf("open %s failed: %v", ctx.Args().First(), err)
+	}
+	defer input.Close()
+	verifySignature := conf.SwitchConf[config.BlockSwitch].VerifySignature
+	imported, skipped, err := importBlocks(input, blockFilter.NewBlockFilter(events.NewEvent(), verifySignature))
+	fmt.Fprintf(ctx.App.Writer, "Imported %d blocks, skipped %d existing blocks.\n", imported, skipped)
+	return err
+}
+
+// blockWriter verify block against the chain and write it, as block switch does.
+type blockWriter interface {
+	Verify(portId int, msg interface{}) error
+}
+
+// importBlocks read blocks from reader and write them to chain in order.
+func importBlocks(reader io.Reader, writer blockWriter) (imported int, skipped int, err error) {
+	decoder := json.NewDecoder(reader)
+	for {
+		block := new(types.Block)
+		if err = decoder.Decode(block); io.EOF == err {
+			return imported, skipped, nil
+		} else if nil != err {
+			return imported, skipped, fmt.Errorf("read block failed: %v", err)
+		}
+		if nil == block.Header {
+			return imported, skipped, fmt.Errorf("read block without header")
+		}
+		chain, err := repository.NewLatestStateRepository()
+		if nil != err {
+			return imported, skipped, fmt.Errorf("open repository failed: %v", err)
+		}
+		if block.Header.Height <= chain.GetCurrentBlockHeight() {
+			local, err := chain.GetBlockByHeight(block.Header.Height)
+			if nil != err {
+				return imported, skipped, fmt.Errorf("get local block %d failed: %v", block.Header.Height, err)
+			}
+			if local.HeaderHash != block.HeaderHash {
+				return imported, skipped, fmt.Errorf("block %d is %x, which differs from local block %x",
+					block.Header.Height, block.HeaderHash, local.HeaderHash)
+			}
+			skipped++
+			continue
+		}
+		if err := writer.Verify(0, block); nil != err {
+			return imported, skipped, fmt.Errorf("import block %d failed: %v", block.Header.Height, err)
+		}
+		imported++
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	craftConfig "github.com/DSiSc/craft/config"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/node"
+	"github.com/urfave/cli"
+	"text/tabwriter"
+)
+
+var ConfigCommand = cli.Command{
+	Name:  "config",
+	Usage: "Node config tools",
+	Subcommands: []cli.Command{
+		{
+			Name:   "validate",
+			Usage:  "Check every field of node config and that genesis file could be loaded",
+			Flags:  withGlobalFlags(),
+			Action: action(validateConfig),
+		},
+		{
+			Name:  "show",
+			Usage: "Print the resolved node config with the source of each value",
+			Description: `Each value is tagged with default, file, env or flag. Values in file are overridden by
+JUSTITIA_* environment variables, and file log setting by --log_path, --log_level and --log_style.`,
+			Flags:  withGlobalFlags(),
+			Action: action(showConfig),
+		},
+	},
+}
+
+// loadNodeConfig read node config, and set the hash algorithm as node does.
+func loadNodeConfig() (config.NodeConfig, error) {
+	conf, err := config.LoadNodeConfig()
+	if nil != err {
+		return conf, err
+	}
+	craftConfig.GlobalConfig.Store(craftConfig.HashAlgName, conf.AlgorithmConf.HashAlgorithm)
+	return conf, nil
+}
+
+func validateConfig(ctx *cli.Context) error {
+	if _, err := loadNodeConfig(); nil != err {
+		problems, ok := err.(config.ValidationError)
+		if !ok {
+			return err
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(ctx.App.Writer, problem.Error())
+		}
+		return fmt.Errorf("invalid fields in node config: %d", len(problems))
+	}
+	if _, err := config.GenerateGenesisBlock(); nil != err {
+		return fmt.Errorf("invalid genesis file: %v", err)
+	}
+	fmt.Fprintln(ctx.App.Writer, "Config is valid.")
+	return nil
+}
+
+func showConfig(ctx *cli.Context) error {
+	conf, err := node.ReloadConfig(sysConfig(ctx))
+	if nil != err {
+		return err
+	}
+	fmt.Fprintf(ctx.App.Writer, "Home: %s\n", config.Home())
+	writer := tabwriter.NewWriter(ctx.App.Writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "KEY\tVALUE\tSOURCE")
+	for _, value := range config.DescribeNodeConfig(conf) {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", value.Key, value.Value, value.Source)
+	}
+	return writer.Flush()
+}
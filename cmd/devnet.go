@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/justitia/devnet"
+	"github.com/DSiSc/justitia/tools/signal"
+	"github.com/urfave/cli"
+	"os"
+	"sync"
+	"syscall"
+)
+
+var (
+	devnetNodesFlag = cli.IntFlag{
+		Name:  "nodes",
+		Usage: "Number of consensus nodes",
+		Value: 4,
+	}
+	devnetConsensusFlag = cli.StringFlag{
+		Name:  "consensus",
+		Usage: "Consensus policy, which choose from [solo, bft, fbft, dbft]",
+		Value: "fbft",
+	}
+	devnetDirFlag = cli.StringFlag{
+		Name:  "dir",
+		Usage: "Directory to hold the home directory of each node",
+		Value: "devnet",
+	}
+	devnetBasePortFlag = cli.IntFlag{
+		Name:  "base-port",
+		Usage: fmt.Sprintf("First port of the devnet, each node takes %d ports", devnet.PortsPerNode),
+		Value: 30000,
+	}
+	devnetChainIdFlag = cli.Uint64Flag{
+		Name:  "chain-id",
+		Usage: "Chain id recorded in genesis block",
+	}
+	devnetIntervalFlag = cli.Int64Flag{
+		Name:  "interval",
+		Usage: "Block produce interval in millisecond, keep the default one if 0",
+	}
+	devnetGenerateOnlyFlag = cli.BoolFlag{
+		Name:  "generate-only",
+		Usage: "Only generate node files without running nodes",
+	}
+)
+
+var DevnetCommand = cli.Command{
+	Name:  "devnet",
+	Usage: "Generate and run a local network of several nodes",
+	Flags: []cli.Flag{
+		devnetNodesFlag,
+		devnetConsensusFlag,
+		devnetDirFlag,
+		devnetBasePortFlag,
+		devnetChainIdFlag,
+		devnetIntervalFlag,
+		devnetGenerateOnlyFlag,
+	},
+	Action: runDevnet,
+}
+
+// runDevnet generate a local devnet from command line flags, and run its nodes until interrupted.
+func runDevnet(ctx *cli.Context) error {
+	devnetNodes, err := devnet.Generate(devnet.Config{
+		Nodes:         ctx.Int(devnetNodesFlag.Name),
+		Consensus:     ctx.String(devnetConsensusFlag.Name),
+		Dir:           ctx.String(devnetDirFlag.Name),
+		BasePort:      ctx.Int(devnetBasePortFlag.Name),
+		ChainId:       ctx.Uint64(devnetChainIdFlag.Name),
+		BlockInterval: ctx.Int64(devnetIntervalFlag.Name),
+	})
+	if nil != err {
+		return fmt.Errorf("generate devnet failed: %v", err)
+	}
+	for _, node := range devnetNodes {
+		fmt.Fprintf(ctx.App.Writer, "node%d address %x api %s admin %s home %s\n",
+			node.Index, node.Address, node.ApiGateway(), node.AdminGateway(), node.Home)
+	}
+	if ctx.Bool(devnetGenerateOnlyFlag.Name) {
+		return nil
+	}
+	binary, err := os.Executable()
+	if nil != err {
+		return fmt.Errorf("locate justitia binary failed: %v", err)
+	}
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	sysSignalProcess := signal.NewSignalSet()
+	stopDevnet := func(sig os.Signal, _ interface{}) {
+		log.Warn("handle signal %v, stop devnet.", sig)
+		stopOnce.Do(func() { close(stop) })
+	}
+	sysSignalProcess.RegisterSysSignal(syscall.SIGINT, stopDevnet)
+	sysSignalProcess.RegisterSysSignal(syscall.SIGTERM, stopDevnet)
+	go sysSignalProcess.CatchSysSignal()
+	if err := devnet.Run(binary, devnetNodes, stop, shutdownTimeout); nil != err {
+		return fmt.Errorf("devnet stopped with error: %v", err)
+	}
+	log.Info("Devnet stopped.")
+	return nil
+}
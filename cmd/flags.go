@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/urfave/cli"
+)
+
+var (
+	HomeFlag = cli.StringFlag{
+		Name:   "home",
+		Usage:  "Root of config, data, p2p and logs directories, default to ~/.justitia",
+		EnvVar: config.HomeEnv,
+	}
+	ConfigFlag = cli.StringFlag{
+		Name:  "config",
+		Usage: "Config file used instead of config/justitia.yaml in home directory",
+	}
+	LogLevelFlag = cli.IntFlag{
+		Name:  "log_level",
+		Usage: "Log level [0: debug, 1: info, 2: warn, 3: error, 4: fatal, 5: panic, 6: disable]",
+		Value: common.InvalidInt,
+	}
+	LogPathFlag = cli.StringFlag{
+		Name:  "log_path",
+		Usage: "Log output file, relative path is under home directory",
+	}
+	LogStyleFlag = cli.StringFlag{
+		Name:  "log_style",
+		Usage: "Log output style in json or text, which choose from [json, text]",
+	}
+
+	// flags accepted by every command, either before or after the command name.
+	globalFlags = []cli.Flag{
+		HomeFlag,
+		ConfigFlag,
+		LogLevelFlag,
+		LogPathFlag,
+		LogStyleFlag,
+	}
+)
+
+// lookupString return the value of flag from the innermost command it is set on.
+func lookupString(ctx *cli.Context, name string) string {
+	for c := ctx; nil != c; c = c.Parent() {
+		if c.IsSet(name) {
+			return c.String(name)
+		}
+	}
+	return common.BlankString
+}
+
+func lookupInt(ctx *cli.Context, name string, value int) int {
+	for c := ctx; nil != c; c = c.Parent() {
+		if c.IsSet(name) {
+			return c.Int(name)
+		}
+	}
+	return value
+}
+
+// sysConfig return the log settings given by command line.
+func sysConfig(ctx *cli.Context) config.SysConfig {
+	style := lookupString(ctx, LogStyleFlag.Name)
+	switch style {
+	case "text":
+		style = log.TextFmt
+	case "json":
+		style = log.JsonFmt
+	}
+	return config.SysConfig{
+		LogLevel: log.Level(lookupInt(ctx, LogLevelFlag.Name, common.InvalidInt)),
+		LogPath:  lookupString(ctx, LogPathFlag.Name),
+		LogStyle: style,
+	}
+}
+
+// action apply home directory and config file given by command line before running run.
+func action(run func(ctx *cli.Context) error) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		if home := lookupString(ctx, HomeFlag.Name); common.BlankString != home {
+			config.SetHome(home)
+		}
+		if file := lookupString(ctx, ConfigFlag.Name); common.BlankString != file {
+			config.SetConfigFile(file)
+		}
+		return run(ctx)
+	}
+}
+
+// withGlobalFlags return flags of a command together with global flags.
+func withGlobalFlags(flags ...cli.Flag) []cli.Flag {
+	return append(flags, globalFlags...)
+}
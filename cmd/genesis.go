@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	justitiac "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	genesisAccountFlag = cli.StringSliceFlag{
+		Name:  "account",
+		Usage: "Funded account as <address>=<balance>, could be repeated",
+	}
+	genesisParticipateFlag = cli.StringSliceFlag{
+		Name:  "participate",
+		Usage: "Consensus participate as <address>@<host:port>, could be repeated, ids are numbered from 0 in order",
+	}
+	genesisChainIdFlag = cli.Uint64Flag{
+		Name:  "chain-id",
+		Usage: "Chain id of genesis block",
+	}
+	genesisContractFlag = cli.StringSliceFlag{
+		Name:  "contract",
+		Usage: "System contract deployed by genesis block, one of " + strings.Join(config.SystemContracts, ", "),
+	}
+	genesisPrebuiltFlag = cli.BoolFlag{
+		Name:  "prebuilt",
+		Usage: "Deploy the prebuilt code of system contracts in default genesis file, instead of compiling them with solc",
+	}
+	genesisOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "Genesis file to write, default to config/genesis.json in home directory",
+	}
+	genesisHeightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "Height of the block whose state is exported, default to current height",
+		Value: config.LatestHeight,
+	}
+)
+
+var GenesisCommand = cli.Command{
+	Name:  "genesis",
+	Usage: "Genesis block tools",
+	Subcommands: []cli.Command{
+		{
+			Name:   "show",
+			Usage:  "Print hash, chain id, accounts, system contracts and participates of genesis block",
+			Flags:  withGlobalFlags(),
+			Action: action(showGenesis),
+		},
+		{
+			Name:  "new",
+			Usage: "Write a genesis file with funded accounts, participates and system contracts",
+			Description: `System contracts are compiled by the installed solc from their source built into justitia,
+which are ` + strings.Join(config.CompiledContracts(), ", ") + `. With --prebuilt, they are deployed with their
+prebuilt code in the default genesis file instead, which ` + strings.Join(config.PrebuiltContracts(), ", ") + `
+have. Contracts having neither are rejected.`,
+			Flags: withGlobalFlags(genesisAccountFlag, genesisParticipateFlag, genesisChainIdFlag,
+				genesisContractFlag, genesisPrebuiltFlag, genesisOutputFlag, forceFlag),
+			Action: action(newGenesis),
+		},
+		{
+			Name:      "export",
+			Usage:     "Write a genesis file with the state of a block, to restart the network from it",
+			ArgsUsage: "<file>",
+			Description: `Balance, nonce, code and storage of accounts are read from the repository in node config,
+so node should be stopped first. Chain id, participates and signature algorithm are copied from
+current genesis file, and could be changed before the new network is started.`,
+			Flags:  withGlobalFlags(genesisHeightFlag, forceFlag),
+			Action: action(exportGenesis),
+		},
+	},
+}
+
+func showGenesis(ctx *cli.Context) error {
+	if _, err := loadNodeConfig(); nil != err {
+		return err
+	}
+	genesis, err := config.GenerateGenesisBlock()
+	if nil != err {
+		return err
+	}
+	writer := ctx.App.Writer
+	fmt.Fprintf(writer, "Hash:     %x\n", justitiac.HeaderHash(genesis.Block))
+	fmt.Fprintf(writer, "Chain id: %d\n", genesis.Block.Header.ChainID)
+	fmt.Fprintf(writer, "Time:     %s\n", time.Unix(int64(genesis.Block.Header.Timestamp), 0).UTC().Format(time.RFC3339))
+	fmt.Fprintf(writer, "Coinbase: 0x%x\n", genesis.Block.Header.CoinBase)
+	if len(genesis.Block.Header.Extra) > 0 {
+		fmt.Fprintf(writer, "Extra:    0x%x\n", genesis.Block.Header.Extra)
+	}
+	fmt.Fprintln(writer, "Accounts:")
+	for _, account := range genesis.GenesisAccounts {
+		kind := "code"
+		if account.IsWasm() {
+			kind = "wasm"
+		}
+		fmt.Fprintf(writer, "  %x balance %v %s %d bytes %s\n", account.Addr, account.Balance, kind, len(account.Code), account.Contract)
+	}
+	if contracts := genesis.SystemContracts(); len(contracts) > 0 {
+		names := make([]string, 0, len(contracts))
+		for name := range contracts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(writer, "System contracts:")
+		for _, name := range names {
+			fmt.Fprintf(writer, "  %s 0x%x\n", name, contracts[name])
+		}
+	}
+	if len(genesis.Participates) > 0 {
+		fmt.Fprintln(writer, "Participates:")
+		for _, participate := range genesis.Participates {
+			fmt.Fprintf(writer, "  %d %s %s\n", participate.Id, participate.Addr, participate.Url)
+		}
+	}
+	return nil
+}
+
+func exportGenesis(ctx *cli.Context) error {
+	if 1 != ctx.NArg() {
+		return fmt.Errorf("export needs exactly one file argument")
+	}
+	output := ctx.Args().First()
+	if !ctx.Bool(forceFlag.Name) && tools.PathExists(output) {
+		return fmt.Errorf("%s already exists, use --%s to overwrite it", output, forceFlag.Name)
+	}
+	conf, err := loadNodeConfig()
+	if nil != err {
+		return err
+	}
+	genesis, export, err := config.ExportGenesis(conf.RepositoryConf, ctx.Uint64(genesisHeightFlag.Name))
+	if nil != err {
+		return err
+	}
+	content, err := json.MarshalIndent(genesis, "", "  ")
+	if nil != err {
+		return fmt.Errorf("encode genesis failed: %v", err)
+	}
+	if err := ioutil.WriteFile(output, append(content, '\n'), 0644); nil != err {
+		return fmt.Errorf("write %s failed: %v", output, err)
+	}
+	writer := ctx.App.Writer
+	fmt.Fprintf(writer, "Genesis file written to %s.\n", output)
+	fmt.Fprintf(writer, "Height:     %d\n", export.Height)
+	fmt.Fprintf(writer, "Block:      %x\n", export.BlockHash)
+	fmt.Fprintf(writer, "State root: %x\n", export.StateRoot)
+	fmt.Fprintf(writer, "Accounts:   %d, %d of them are contracts, %d of them are empty\n", export.Accounts, export.Contracts, export.Empty)
+	return nil
+}
+
+func newGenesis(ctx *cli.Context) error {
+	spec := config.GenesisSpec{
+		ChainId:   ctx.Uint64(genesisChainIdFlag.Name),
+		Contracts: ctx.StringSlice(genesisContractFlag.Name),
+		Prebuilt:  ctx.Bool(genesisPrebuiltFlag.Name),
+	}
+	for _, account := range ctx.StringSlice(genesisAccountFlag.Name) {
+		fields := strings.SplitN(account, "=", 2)
+		if 2 != len(fields) {
+			return fmt.Errorf("invalid account %s, which should be <address>=<balance>", account)
+		}
+		balance, ok := new(big.Int).SetString(fields[1], 10)
+		if !ok {
+			return fmt.Errorf("invalid balance %s of account %s", fields[1], fields[0])
+		}
+		spec.Accounts = append(spec.Accounts, config.GenesisAccountConfig{Addr: fields[0], Balance: balance})
+	}
+	for index, participate := range ctx.StringSlice(genesisParticipateFlag.Name) {
+		fields := strings.SplitN(participate, "@", 2)
+		if 2 != len(fields) {
+			return fmt.Errorf("invalid participate %s, which should be <address>@<host:port>", participate)
+		}
+		spec.Participates = append(spec.Participates, config.GenesisParticipate{Addr: fields[0], Id: uint64(index), Url: fields[1]})
+	}
+	genesisConfig, err := config.NewGenesisConfig(spec)
+	if nil != err {
+		return err
+	}
+	content, err := json.MarshalIndent(genesisConfig, "", "  ")
+	if nil != err {
+		return fmt.Errorf("encode genesis failed: %v", err)
+	}
+	// block is filled with timestamp and deploying transactions by NewGenesisBlock, so it is encoded before.
+	genesis, err := config.NewGenesisBlock(genesisConfig)
+	if nil != err {
+		return err
+	}
+
+	output := ctx.String(genesisOutputFlag.Name)
+	if justitiac.BlankString == output {
+		output = filepath.Join(config.ConfigDir(), config.GenesisFileName)
+	}
+	if !ctx.Bool(forceFlag.Name) && tools.PathExists(output) {
+		return fmt.Errorf("%s already exists, use --%s to overwrite it", output, forceFlag.Name)
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); nil != err {
+		return fmt.Errorf("create directory of %s failed: %v", output, err)
+	}
+	if err := ioutil.WriteFile(output, append(content, '\n'), 0644); nil != err {
+		return fmt.Errorf("write %s failed: %v", output, err)
+	}
+	fmt.Fprintf(ctx.App.Writer, "Genesis file written to %s.\n", output)
+	fmt.Fprintf(ctx.App.Writer, "Hash: %x\n", justitiac.HeaderHash(genesis.Block))
+	return nil
+}
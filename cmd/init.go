@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var forceFlag = cli.BoolFlag{
+	Name:  "force",
+	Usage: "Overwrite existing config and genesis file",
+}
+
+var InitCommand = cli.Command{
+	Name:   "init",
+	Usage:  "Create home directory with default justitia.yaml and genesis.json",
+	Flags:  withGlobalFlags(forceFlag),
+	Action: action(initHome),
+}
+
+func initHome(ctx *cli.Context) error {
+	home := config.Home()
+	files := map[string][]byte{
+		filepath.Join(config.ConfigDir(), config.ConfigPrefix+".yaml"): config.DefaultConfigFile,
+		filepath.Join(config.ConfigDir(), config.GenesisFileName):      config.DefaultGenesisFile,
+	}
+	if !ctx.Bool(forceFlag.Name) {
+		for file := range files {
+			if tools.PathExists(file) {
+				return fmt.Errorf("%s already exists, use --%s to overwrite it", file, forceFlag.Name)
+			}
+		}
+	}
+	for _, dir := range []string{config.ConfigDirName, config.DataDirName, config.P2PDirName, config.LogDirName} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0755); nil != err {
+			return fmt.Errorf("create directory %s failed: %v", dir, err)
+		}
+	}
+	for file, content := range files {
+		if err := ioutil.WriteFile(file, content, 0644); nil != err {
+			return fmt.Errorf("write %s failed: %v", file, err)
+		}
+	}
+	fmt.Fprintf(ctx.App.Writer, "Initialized justitia home %s.\n", home)
+	return nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/node"
+	"github.com/DSiSc/justitia/tools/signal"
+	"github.com/urfave/cli"
+	"os"
+	"syscall"
+	"time"
+)
+
+// max time to wait for node service to stop
+const shutdownTimeout = 30 * time.Second
+
+var StartCommand = cli.Command{
+	Name:   "start",
+	Usage:  "Start a node with the config in home directory",
+	Flags:  withGlobalFlags(),
+	Action: action(startNode),
+}
+
+func startNode(ctx *cli.Context) error {
+	args := sysConfig(ctx)
+	service, err := node.NewNode(args)
+	if nil != err {
+		log.Error("Failed to initial a node with err %v.", err)
+		return fmt.Errorf("failed to initial a node: %v", err)
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			log.Fatal("Fatal error occur: %v.", err)
+		}
+	}()
+	if err := service.Start(); nil != err {
+		log.Error("Failed to start node service with err %v.", err)
+		return fmt.Errorf("failed to start node service: %v", err)
+	}
+	stopped := sysSignalProcess(service, args)
+	if err := <-stopped; nil != err {
+		log.Error("Stop node service failed with error %v.", err)
+		return fmt.Errorf("stop node service failed: %v", err)
+	}
+	log.Info("Node service stopped.")
+	return nil
+}
+
+// stopNode stop node service within shutdownTimeout.
+func stopNode(service node.NodesService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return service.Stop(ctx)
+}
+
+// reloadNode read config again and apply it to node service.
+func reloadNode(service node.NodesService, args config.SysConfig) {
+	conf, err := node.ReloadConfig(args)
+	if nil != err {
+		log.Error("Reload config failed with error %v, keep running with current config.", err)
+		return
+	}
+	result, err := service.Reload(conf)
+	if nil != err {
+		log.Error("Reload config failed with error %v.", err)
+		return
+	}
+	if 0 == len(result.Applied) && 0 == len(result.RestartRequired) {
+		log.Info("Config not changed.")
+	}
+}
+
+// sysSignalProcess stop node service on SIGINT and SIGTERM, the stop result will be sent to the returned channel.
+// Config is reloaded on SIGHUP.
+func sysSignalProcess(service node.NodesService, args config.SysConfig) <-chan error {
+	stopped := make(chan error, 1)
+	sysSignalProcess := signal.NewSignalSet()
+	sysSignalProcess.RegisterSysSignal(syscall.SIGINT, func(os.Signal, interface{}) {
+		log.Warn("handle signal SIGINT.")
+		stopped <- stopNode(service)
+	})
+	sysSignalProcess.RegisterSysSignal(syscall.SIGTERM, func(os.Signal, interface{}) {
+		log.Warn("handle signal SIGTERM.")
+		stopped <- stopNode(service)
+	})
+	sysSignalProcess.RegisterSysSignal(syscall.SIGHUP, func(os.Signal, interface{}) {
+		log.Warn("handle signal SIGHUP.")
+		reloadNode(service, args)
+	})
+	go sysSignalProcess.CatchSysSignal()
+	return stopped
+}
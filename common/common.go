@@ -122,18 +122,20 @@ const (
 	MsgWaitTimeOut
 )
 
+// systemContractTypes map names of system contracts in genesis file to their types.
+var systemContractTypes = map[string]types.ContractType{
+	types.JustitiaRightToken:     types.JustitiaRightContractType,
+	types.JustitiaVoting:         types.VoteContractType,
+	types.JustitiaWhiteList:      types.WhiteListContractType,
+	types.JustitiaMetaData:       types.MetaDataContractType,
+	types.JustitiaCrossFundsPool: types.CrossFundsPoolContractType,
+}
+
+// SystemContractType return the type of system contract named contractType, or InitialContractType if it is
+// not a system contract.
 func SystemContractType(contractType string) types.ContractType {
-	var contract = types.InitialContractType
-	if contractType == types.JustitiaRightToken {
-		contract = types.JustitiaRightContractType
-	} else if contractType == types.JustitiaVoting {
-		contract = types.VoteContractType
-	} else if contractType == types.JustitiaWhiteList {
-		contract = types.WhiteListContractType
-	} else if contractType == types.JustitiaMetaData {
-		contract = types.MetaDataContractType
-	} else if contractType == types.JustitiaCrossFundsPool {
-		contract = types.CrossFundsPoolContractType
+	if contract, ok := systemContractTypes[contractType]; ok {
+		return contract
 	}
-	return contract
+	return types.InitialContractType
 }
@@ -20,19 +20,23 @@ package compiler
 import (
 	"bufio"
 	"bytes"
+	"embed"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/DSiSc/justitia/tools"
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+// sources of contracts which could be compiled by name
+//
+//go:embed contracts/*.sol
+var contractSources embed.FS
+
 var versionRegexp = regexp.MustCompile(`([0-9]+)\.([0-9]+)\.([0-9]+)`)
 
 // Contract contains information about a compiled contract, alongside its code and runtime code.
@@ -115,27 +119,48 @@ func SolidityVersion() (*Solidity, error) {
 	return s, nil
 }
 
+// SolidityCompile compile the contract named source in contracts directory, whose sources are built into binary.
 func SolidityCompile(source string) string {
-	sourcePath := fmt.Sprintf("src/github.com/DSiSc/justitia/compiler/contracts/%s.sol", source)
-	var absolutePath = ""
-	goPath := os.Getenv("GOPATH")
-	for _, p := range filepath.SplitList(goPath) {
-		cpath := filepath.Join(p, sourcePath)
-		if tools.PathExists(cpath) {
-			absolutePath = cpath
-			break
-		}
+	code, err := CompileContract(source)
+	if nil != err {
+		panic(err.Error())
 	}
-	contract, err := CompileSolidityString(absolutePath)
+	return code
+}
+
+// HasContract report whether source of the named contract is built into binary.
+func HasContract(name string) bool {
+	_, err := contractSources.ReadFile(fmt.Sprintf("contracts/%s.sol", name))
+	return nil == err
+}
+
+// CompileContract compile the named contract in contracts directory, and return its hex encoded code.
+func CompileContract(name string) (string, error) {
+	code, err := contractSources.ReadFile(fmt.Sprintf("contracts/%s.sol", name))
 	if nil != err {
-		panic("info for contract 'test' not present in result")
+		return "", fmt.Errorf("source of contract %s not found", name)
+	}
+	contract, err := compileSoliditySource(code)
+	if nil != err {
+		return "", fmt.Errorf("compile contract %s failed: %v", name, err)
 	}
-	key := fmt.Sprintf("<stdin>:%s", source)
-	c, ok := contract[key]
+	c, ok := contract[fmt.Sprintf("<stdin>:%s", name)]
 	if !ok {
-		panic("info for contract 'test' not present in result")
+		return "", fmt.Errorf("info for contract %s not present in result", name)
+	}
+	return c.Code, nil
+}
+
+// compileSoliditySource compile source passed to solc by stdin.
+func compileSoliditySource(source []byte) (map[string]*Contract, error) {
+	s, err := SolidityVersion()
+	if err != nil {
+		return nil, err
 	}
-	return c.Code
+	args := append(s.makeArgs(), "--")
+	cmd := exec.Command(s.Path, append(args, "-")...)
+	cmd.Stdin = bytes.NewReader(source)
+	return s.run(cmd, string(source))
 }
 
 // CompileSolidityString builds and returns all the contracts contained within a source string.
@@ -92,3 +92,16 @@ func TestSolidityCompile(t *testing.T) {
 	contractCode := SolidityCompile(contractName)
 	assert.Equal(t, byteCode, contractCode)
 }
+
+func TestCompileContract(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(HasContract(contractName))
+	assert.False(HasContract("CrossFundsPool"))
+	_, err := CompileContract("CrossFundsPool")
+	assert.NotNil(err)
+
+	skipWithoutSolc(t)
+	code, err := CompileContract(contractName)
+	assert.Nil(err)
+	assert.Equal(byteCode, code)
+}
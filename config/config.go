@@ -9,6 +9,7 @@ import (
 	roleConfig "github.com/DSiSc/galaxy/role/config"
 	swConf "github.com/DSiSc/gossipswitch/config"
 	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/propagator"
 	"github.com/DSiSc/justitia/tools"
 	p2pConf "github.com/DSiSc/p2p/config"
 	producerConfig "github.com/DSiSc/producer/config"
@@ -18,13 +19,14 @@ import (
 	"github.com/spf13/viper"
 	"math"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
 const (
 	// config file prefix
 	ConfigPrefix = "justitia"
+	// environment variable of the directory holding config and genesis file
+	HomeEnv = "JUSTITIA_HOME"
 	// node type
 	NodeType = "general.nodeType"
 	// algorithm setting
@@ -54,6 +56,8 @@ const (
 	RepositoryDataPath  = "general.repository.dataPath"
 	// api gateway
 	ApiGatewayAddr = "general.apigateway"
+	// admin gateway, leave empty to disable admin rpc
+	AdminGatewayAddr = "general.admingateway"
 	// Default parameter for solo block producer
 	BlockProducedTimeInterval = "general.BlockProducedInterval"
 
@@ -74,6 +78,11 @@ const (
 	P2PDNSSeeds        = "DNSSeeds"
 	P2PService         = "Service"
 
+	// monitor settings
+	PrometheusSetting = "monitor.prometheus"
+	ExpvarSetting     = "monitor.expvar"
+	PprofSetting      = "monitor.pprof"
+
 	// prometheus
 	PrometheusEnabled = "monitor.prometheus.enabled"
 	PrometheusPort    = "monitor.prometheus.port"
@@ -89,6 +98,7 @@ const (
 	PprofPort    = "monitor.pprof.port"
 
 	// Log Setting
+	LogSetting         = "logging"
 	LogTimeFieldFormat = "logging.timeFieldFormat"
 	ConsoleLogAppender = "logging.console"
 	LogConsoleEnabled  = "logging.console.enabled"
@@ -113,8 +123,21 @@ const (
 	TxSwitchSignatureVerifySwitch    = "general.signature.txswitch"
 	BlockSwitch                      = "block_switch"
 	BlockSwitchSignatureVerifySwitch = "general.signature.blockswitch"
+
+	// light node
+	LightFullPeers        = "general.light.fullPeers"
+	LightHeaderCacheLimit = "general.light.headerCacheLimit"
+	LightValidators       = "general.light.validators"
+
+	// propagator
+	PropagatorTxQueueSize    = "general.propagator.txQueueSize"
+	PropagatorBlockQueueSize = "general.propagator.blockQueueSize"
+	PropagatorDropPolicy     = "general.propagator.dropPolicy"
 )
 
+// default number of recent headers kept by light node
+const DefaultLightHeaderCacheLimit = 1024
+
 type AlgorithmConfig struct {
 	//hash algorithm
 	HashAlgorithm string
@@ -122,6 +145,23 @@ type AlgorithmConfig struct {
 	SignAlgorithm string
 }
 
+type LightConfig struct {
+	// rpc addresses of full nodes, which serve account state to light node
+	FullPeers []string
+	// number of recent headers kept in memory
+	HeaderCacheLimit uint64
+	// addresses of consensus nodes signing headers, participates in genesis file are used if empty
+	Validators []string
+}
+
+type PropagatorConfig struct {
+	// number of received transactions and blocks waiting for gossip switches
+	TxQueueSize    int
+	BlockQueueSize int
+	// policy dropping received items when queue is full, oldest or lowestScore
+	DropPolicy string
+}
+
 type SysConfig struct {
 	LogLevel log.Level
 	LogPath  string
@@ -134,6 +174,8 @@ type NodeConfig struct {
 	Account account.Account
 	// api gateway
 	ApiGatewayAddr string
+	// admin gateway
+	AdminGatewayAddr string
 	// txpool
 	TxPoolConf txpool.TxPoolConfig
 	// participates
@@ -163,6 +205,12 @@ type NodeConfig struct {
 	P2PConf map[string]*p2pConf.P2PConfig
 	//Switch config
 	SwitchConf map[string]*swConf.SwitchConfig
+	// light node config
+	LightConf LightConfig
+	// propagator config
+	PropagatorConf PropagatorConfig
+	// source of each key in justitia.yaml, which is one of default, file, env and flag
+	Sources map[string]string
 }
 
 type Config struct {
@@ -170,36 +218,41 @@ type Config struct {
 	maps     map[string]interface{}
 }
 
-func LoadConfig() (config *viper.Viper) {
-	config = viper.New()
+// LoadConfig read justitia.yaml, overridden by environment variables.
+func LoadConfig() (*viper.Viper, error) {
+	config := viper.New()
 	// for environment variables
 	config.SetEnvPrefix(ConfigPrefix)
 	config.AutomaticEnv()
 	replacer := strings.NewReplacer(".", "_")
 	config.SetEnvKeyReplacer(replacer)
 
-	config.SetConfigName("justitia")
-	homePath, _ := tools.Home()
-	config.AddConfigPath(fmt.Sprintf("%s/.justitia", homePath))
-	// Path to look for the config file in based on GOPATH
-	goPath := os.Getenv("GOPATH")
-	for _, p := range filepath.SplitList(goPath) {
-		config.AddConfigPath(filepath.Join(p, "src/github.com/DSiSc/justitia/config"))
+	if common.BlankString != configFile {
+		config.SetConfigFile(configFile)
+	} else {
+		config.SetConfigName("justitia")
+		for _, dir := range configDirs() {
+			config.AddConfigPath(dir)
+		}
 	}
 
-	err := config.ReadInConfig()
-	if err != nil {
-		panic(fmt.Errorf("error reading plugin config: %s", err))
+	if err := config.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading plugin config: %s", err)
 	}
-	return
+	return config, nil
 }
 
-func NewNodeConfig() NodeConfig {
-	config := LoadConfig()
+// NewNodeConfig read node config from justitia.yaml and genesis file.
+func NewNodeConfig() (NodeConfig, error) {
+	config, err := LoadConfig()
+	if nil != err {
+		return NodeConfig{}, err
+	}
 	nodeType := getNodeType(config)
 	algorithmConf := GetAlgorithmConf(config)
 	nodeAccount := GetNodeAccount(config)
 	apiGatewayTcpAddr := GetApiGatewayTcpAddr(config)
+	adminGatewayTcpAddr := GetAdminGatewayTcpAddr(config)
 	txPoolConf := NewTxPoolConf(config)
 	participatesConf := NewParticipateConf(config)
 	roleConf := NewRoleConf(config)
@@ -211,12 +264,20 @@ func NewNodeConfig() NodeConfig {
 	pprofConf := GetPprofConf(config)
 	logConf := GetLogSetting(config)
 	p2pConf := GetP2PConf(config)
-	producerConf := GetProducerConf(config)
-	switchConf := GetSwitchConf(config)
+	// chain id is read once, as genesis file is parsed again on every read.
+	chainId, err := GetChainIdFromConfig()
+	if nil != err {
+		return NodeConfig{}, fmt.Errorf("read chain id from genesis file failed: %v", err)
+	}
+	producerConf := GetProducerConf(config, chainId)
+	switchConf := GetSwitchConf(config, chainId)
+	lightConf := GetLightConf(config)
+	propagatorConf := GetPropagatorConf(config)
 	return NodeConfig{
 		Account:          nodeAccount,
 		NodeType:         nodeType,
 		ApiGatewayAddr:   apiGatewayTcpAddr,
+		AdminGatewayAddr: adminGatewayTcpAddr,
 		TxPoolConf:       txPoolConf,
 		ParticipatesConf: participatesConf,
 		RoleConf:         roleConf,
@@ -231,7 +292,25 @@ func NewNodeConfig() NodeConfig {
 		P2PConf:          p2pConf,
 		ProducerConf:     producerConf,
 		SwitchConf:       switchConf,
+		LightConf:        lightConf,
+		PropagatorConf:   propagatorConf,
+		Sources:          valueSources(config),
+	}, nil
+}
+
+// LoadNodeConfig read justitia.yaml and validate it, an error is returned if it could not be read,
+// and all invalid fields are reported at once as ValidationError.
+func LoadNodeConfig() (NodeConfig, error) {
+	conf, err := NewNodeConfig()
+	if nil != err {
+		log.Error("Load node config failed with error %v.", err)
+		return conf, fmt.Errorf("load node config failed: %v", err)
 	}
+	if err = Validate(conf); nil != err {
+		log.Error("Load node config failed with error %v.", err)
+		return conf, err
+	}
+	return conf, nil
 }
 
 func GetAlgorithmConf(config *viper.Viper) AlgorithmConfig {
@@ -295,8 +374,8 @@ func NewConsensusConf(conf *viper.Viper) consensusConfig.ConsensusConfig {
 
 func NewRepositoryConf(conf *viper.Viper) repositoryConfig.RepositoryConfig {
 	policy := conf.GetString(RepositoryPlugin)
-	dataPath := conf.GetString(RepositoryDataPath)
-	statePath := conf.GetString(RepositoryStatePath)
+	dataPath := ResolvePath(conf.GetString(RepositoryDataPath), DefaultBlockPath)
+	statePath := ResolvePath(conf.GetString(RepositoryStatePath), DefaultStatePath)
 	RepositoryConf := repositoryConfig.RepositoryConfig{
 		PluginName:    policy,
 		StateDataPath: statePath,
@@ -310,6 +389,11 @@ func GetApiGatewayTcpAddr(conf *viper.Viper) string {
 	return apiGatewayAddr
 }
 
+func GetAdminGatewayTcpAddr(conf *viper.Viper) string {
+	adminGatewayAddr := conf.GetString(AdminGatewayAddr)
+	return adminGatewayAddr
+}
+
 func GetNodeAccount(conf *viper.Viper) account.Account {
 	nodeAddr := conf.GetString(NodeAddress)
 	address := tools.HexToAddress(nodeAddr)
@@ -360,7 +444,7 @@ func GetLogSetting(conf *viper.Viper) log.Config {
 	logConsoleCaller := conf.GetBool(LogConsoleCaller)
 	logConsoleHostname := conf.GetBool(LogConsoleHostname)
 	logFileEnabled := conf.GetBool(LogFileEnabled)
-	logFilePath := conf.GetString(LogFilePath)
+	logFilePath := ResolvePath(conf.GetString(LogFilePath), DefaultLogPath)
 	logFileLevel := conf.GetInt(LogFileLevel)
 	logFileFormat := conf.GetString(LogFileFormat)
 	logFileCaller := conf.GetBool(LogFileCaller)
@@ -412,7 +496,7 @@ func GetP2PConf(conf *viper.Viper) map[string]*p2pConf.P2PConfig {
 }
 
 func getP2PConf(p2pType string, conf *viper.Viper) *p2pConf.P2PConfig {
-	addrFile := conf.GetString(p2pType + "." + P2PAddrBook)
+	addrFile := ResolvePath(conf.GetString(p2pType+"."+P2PAddrBook), DefaultAddrBooks[p2pType])
 	listenAddr := conf.GetString(p2pType + "." + P2PListenAddr)
 	maxOut := conf.GetInt(p2pType + "." + P2PMaxOut)
 	maxIn := conf.GetInt(p2pType + "." + P2PMaxIn)
@@ -440,42 +524,68 @@ func getP2PConf(p2pType string, conf *viper.Viper) *p2pConf.P2PConfig {
 	}
 }
 
-func GetSwitchConf(conf *viper.Viper) map[string]*swConf.SwitchConfig {
+func GetSwitchConf(conf *viper.Viper, chainId uint64) map[string]*swConf.SwitchConfig {
 	swConfig := make(map[string]*swConf.SwitchConfig)
-	swConfig[TxSwitxh] = getTxSwitchConf(conf)
-	swConfig[BlockSwitch] = getBlockSwitchConf(conf)
+	swConfig[TxSwitxh] = getTxSwitchConf(conf, chainId)
+	swConfig[BlockSwitch] = getBlockSwitchConf(conf, chainId)
 	return swConfig
 }
 
-func getTxSwitchConf(conf *viper.Viper) *swConf.SwitchConfig {
+func getTxSwitchConf(conf *viper.Viper, chainId uint64) *swConf.SwitchConfig {
 	enableSignVerify := conf.GetBool(TxSwitchSignatureVerifySwitch)
-	chainId, _ := GetChainIdFromConfig()
 	return &swConf.SwitchConfig{
 		VerifySignature: enableSignVerify,
 		ChainID:         chainId,
 	}
 }
 
-func getBlockSwitchConf(conf *viper.Viper) *swConf.SwitchConfig {
+func getBlockSwitchConf(conf *viper.Viper, chainId uint64) *swConf.SwitchConfig {
 	enableSignVerify := conf.GetBool(BlockSwitchSignatureVerifySwitch)
-	chainId, _ := GetChainIdFromConfig()
 	return &swConf.SwitchConfig{
 		VerifySignature: enableSignVerify,
 		ChainID:         chainId,
 	}
 }
 
+// getNodeType return node type in config, unknown types are reported by Validate.
 func getNodeType(conf *viper.Viper) common.NodeType {
-	nodeType := common.NodeType(conf.GetInt(NodeType))
-	if (nodeType) <= common.UnknownNode || nodeType >= common.MaxNodeType {
-		panic(fmt.Errorf("unknown node type of %d", nodeType))
+	return common.NodeType(conf.GetInt(NodeType))
+}
+
+func GetLightConf(conf *viper.Viper) LightConfig {
+	cacheLimit := conf.GetInt64(LightHeaderCacheLimit)
+	if cacheLimit <= 0 {
+		cacheLimit = DefaultLightHeaderCacheLimit
+	}
+	return LightConfig{
+		FullPeers:        conf.GetStringSlice(LightFullPeers),
+		HeaderCacheLimit: uint64(cacheLimit),
+		Validators:       conf.GetStringSlice(LightValidators),
+	}
+}
+
+func GetPropagatorConf(conf *viper.Viper) PropagatorConfig {
+	txQueueSize := conf.GetInt(PropagatorTxQueueSize)
+	if txQueueSize <= 0 {
+		txQueueSize = propagator.DefaultTxQueueSize
+	}
+	blockQueueSize := conf.GetInt(PropagatorBlockQueueSize)
+	if blockQueueSize <= 0 {
+		blockQueueSize = propagator.DefaultBlockQueueSize
+	}
+	dropPolicy := conf.GetString(PropagatorDropPolicy)
+	if common.BlankString == dropPolicy {
+		dropPolicy = propagator.DropOldest
+	}
+	return PropagatorConfig{
+		TxQueueSize:    txQueueSize,
+		BlockQueueSize: blockQueueSize,
+		DropPolicy:     dropPolicy,
 	}
-	return nodeType
 }
 
-func GetProducerConf(conf *viper.Viper) producerConfig.ProducerConfig {
+func GetProducerConf(conf *viper.Viper, chainId uint64) producerConfig.ProducerConfig {
 	enableSignVerify := conf.GetBool(ProducerSignatureVerifySwitch)
-	chainId, _ := GetChainIdFromConfig()
 	return producerConfig.ProducerConfig{
 		EnableSignatureVerify: enableSignVerify,
 		ChainId:               chainId,
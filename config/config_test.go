@@ -6,15 +6,25 @@ import (
 	"github.com/DSiSc/monkey"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
+// config and genesis files in this directory are used by tests.
+func TestMain(m *testing.M) {
+	os.Setenv(HomeEnv, ".")
+	os.Exit(m.Run())
+}
+
 func Test_NewNodeConfig(t *testing.T) {
 	monkey.Patch(GetLogSetting, func(*viper.Viper) log.Config {
 		return log.Config{}
 	})
 	assert := assert.New(t)
-	nodeConf := NewNodeConfig()
+	nodeConf, err := NewNodeConfig()
+	assert.Nil(err)
 	assert.NotNil(nodeConf)
 	assert.NotNil(nodeConf.AlgorithmConf)
 	assert.Equal("SHA256", nodeConf.AlgorithmConf.HashAlgorithm)
@@ -22,6 +32,12 @@ func Test_NewNodeConfig(t *testing.T) {
 	assert.NotNil("solo", nodeConf.ParticipatesConf.PolicyName)
 	assert.NotNil("solo_node", nodeConf.Account)
 	assert.Equal("tcp://0.0.0.0:47768", nodeConf.ApiGatewayAddr)
+	assert.Equal("tcp://127.0.0.1:47769", nodeConf.AdminGatewayAddr)
+	assert.Equal([]string{"http://127.0.0.1:47768"}, nodeConf.LightConf.FullPeers)
+	assert.Equal(uint64(1024), nodeConf.LightConf.HeaderCacheLimit)
+	assert.Equal(4096, nodeConf.PropagatorConf.TxQueueSize)
+	assert.Equal(64, nodeConf.PropagatorConf.BlockQueueSize)
+	assert.Equal("oldest", nodeConf.PropagatorConf.DropPolicy)
 	assert.Equal(int64(2000), nodeConf.BlockInterval)
 	var address = types.Address{
 		0x33, 0x3c, 0x33, 0x10, 0x82, 0x4b, 0x7c, 0x68, 0x51, 0x33,
@@ -33,3 +49,28 @@ func Test_NewNodeConfig(t *testing.T) {
 	assert.Equal(int64(30000), nodeConf.ConsensusConf.Timeout.TimeoutToChangeView)
 	monkey.UnpatchAll()
 }
+
+func TestLoadConfig_NotFound(t *testing.T) {
+	assert := assert.New(t)
+	SetConfigFile(filepath.Join(t.TempDir(), "justitia.yaml"))
+	defer SetConfigFile("")
+	_, err := LoadConfig()
+	assert.NotNil(err)
+	_, err = LoadNodeConfig()
+	assert.NotNil(err)
+}
+
+func TestNewNodeConfig_InvalidGenesis(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	yaml, err := ioutil.ReadFile("justitia.yaml")
+	assert.Nil(err)
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "justitia.yaml"), yaml, 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, GenesisFileName), []byte("{"), 0644))
+	SetHome(dir)
+	defer SetHome("")
+	_, err = NewNodeConfig()
+	assert.Contains(err.Error(), "read chain id from genesis file failed")
+	_, err = LoadNodeConfig()
+	assert.NotNil(err)
+}
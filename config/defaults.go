@@ -0,0 +1,15 @@
+package config
+
+import (
+	_ "embed"
+)
+
+// DefaultConfigFile is the content of the default justitia.yaml.
+//
+//go:embed justitia.yaml
+var DefaultConfigFile []byte
+
+// DefaultGenesisFile is the content of the default genesis.json.
+//
+//go:embed genesis.json
+var DefaultGenesisFile []byte
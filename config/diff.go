@@ -0,0 +1,129 @@
+package config
+
+import (
+	"github.com/DSiSc/craft/log"
+	p2pConf "github.com/DSiSc/p2p/config"
+	"reflect"
+)
+
+// configValue read the value of a config key from node config.
+type configValue struct {
+	key   string
+	value func(conf *NodeConfig) interface{}
+}
+
+// nodeConfigValues list the keys of justitia.yaml which are kept in node config, in the order of the file.
+var nodeConfigValues = listNodeConfigValues()
+
+func listNodeConfigValues() []configValue {
+	values := []configValue{
+		{NodeType, func(conf *NodeConfig) interface{} { return conf.NodeType }},
+		{HashAlgorithm, func(conf *NodeConfig) interface{} { return conf.AlgorithmConf.HashAlgorithm }},
+		{ApiGatewayAddr, func(conf *NodeConfig) interface{} { return conf.ApiGatewayAddr }},
+		{AdminGatewayAddr, func(conf *NodeConfig) interface{} { return conf.AdminGatewayAddr }},
+		// extension of account is filled from participates when node started.
+		{NodeAddress, func(conf *NodeConfig) interface{} { return conf.Account.Address }},
+		{RepositoryPlugin, func(conf *NodeConfig) interface{} { return conf.RepositoryConf.PluginName }},
+		{RepositoryStatePath, func(conf *NodeConfig) interface{} { return conf.RepositoryConf.StateDataPath }},
+		{RepositoryDataPath, func(conf *NodeConfig) interface{} { return conf.RepositoryConf.BlockDataPath }},
+		{TxpoolSlots, func(conf *NodeConfig) interface{} { return conf.TxPoolConf.GlobalSlots }},
+		{MaxTxBlock, func(conf *NodeConfig) interface{} { return conf.TxPoolConf.MaxTrsPerBlock }},
+		{TxMaxCacheTime, func(conf *NodeConfig) interface{} { return conf.TxPoolConf.TxMaxCacheTime }},
+		{ParticipatesPolicy, func(conf *NodeConfig) interface{} { return conf.ParticipatesConf.PolicyName }},
+		{RolePolicy, func(conf *NodeConfig) interface{} { return conf.RoleConf.PolicyName }},
+		{ConsensusPolicy, func(conf *NodeConfig) interface{} { return conf.ConsensusConf.PolicyName }},
+		{ConsensusEnableEmptyBlock, func(conf *NodeConfig) interface{} { return conf.ConsensusConf.EnableEmptyBlock }},
+		{ConsensusLocalSignatureVerify, func(conf *NodeConfig) interface{} { return conf.ConsensusConf.SignVerifySwitch.LocalVerifySignature }},
+		{ConsensusSyncSignatureVerify, func(conf *NodeConfig) interface{} { return conf.ConsensusConf.SignVerifySwitch.SyncVerifySignature }},
+		{ConsensusTimeoutWaitCommit, func(conf *NodeConfig) interface{} { return conf.ConsensusConf.Timeout.TimeoutToWaitCommitMsg }},
+		{ConsensusTimeoutViewChange, func(conf *NodeConfig) interface{} { return conf.ConsensusConf.Timeout.TimeoutToChangeView }},
+		{ConsensusTimeoutToCollectResponse, func(conf *NodeConfig) interface{} { return conf.ConsensusConf.Timeout.TimeoutToCollectResponseMsg }},
+		{BlockProducedTimeInterval, func(conf *NodeConfig) interface{} { return conf.BlockInterval }},
+		{ProducerSignatureVerifySwitch, func(conf *NodeConfig) interface{} { return conf.ProducerConf.EnableSignatureVerify }},
+		{TxSwitchSignatureVerifySwitch, func(conf *NodeConfig) interface{} { return switchVerifySignature(conf, TxSwitxh) }},
+		{BlockSwitchSignatureVerifySwitch, func(conf *NodeConfig) interface{} { return switchVerifySignature(conf, BlockSwitch) }},
+		{LightFullPeers, func(conf *NodeConfig) interface{} { return conf.LightConf.FullPeers }},
+		{LightHeaderCacheLimit, func(conf *NodeConfig) interface{} { return conf.LightConf.HeaderCacheLimit }},
+		{LightValidators, func(conf *NodeConfig) interface{} { return conf.LightConf.Validators }},
+		{PropagatorTxQueueSize, func(conf *NodeConfig) interface{} { return conf.PropagatorConf.TxQueueSize }},
+		{PropagatorBlockQueueSize, func(conf *NodeConfig) interface{} { return conf.PropagatorConf.BlockQueueSize }},
+		{PropagatorDropPolicy, func(conf *NodeConfig) interface{} { return conf.PropagatorConf.DropPolicy }},
+	}
+	for _, p2pType := range []string{BlockSyncerP2P, BlockP2P, TxP2P} {
+		values = append(values, p2pConfigValues(p2pType)...)
+	}
+	return append(values, []configValue{
+		{PrometheusEnabled, func(conf *NodeConfig) interface{} { return conf.PrometheusConf.PrometheusEnabled }},
+		{PrometheusPort, func(conf *NodeConfig) interface{} { return conf.PrometheusConf.PrometheusPort }},
+		{PrometheusMaxConn, func(conf *NodeConfig) interface{} { return conf.PrometheusConf.PrometheusMaxConn }},
+		{ExpvarEnabled, func(conf *NodeConfig) interface{} { return conf.ExpvarConf.ExpvarEnabled }},
+		{ExpvarPort, func(conf *NodeConfig) interface{} { return conf.ExpvarConf.ExpvarPort }},
+		{ExpvarPath, func(conf *NodeConfig) interface{} { return conf.ExpvarConf.ExpvarPath }},
+		{PprofEnabled, func(conf *NodeConfig) interface{} { return conf.PprofConf.PprofEnabled }},
+		{PprofPort, func(conf *NodeConfig) interface{} { return conf.PprofConf.PprofPort }},
+		{LogTimeFieldFormat, func(conf *NodeConfig) interface{} { return conf.Logger.TimeFieldFormat }},
+		{LogConsoleEnabled, appenderValue(ConsoleLogAppender, func(appender *log.Appender) interface{} { return appender.Enabled })},
+		{LogConsoleLevel, appenderValue(ConsoleLogAppender, func(appender *log.Appender) interface{} { return appender.LogLevel })},
+		{LogConsoleFormat, appenderValue(ConsoleLogAppender, func(appender *log.Appender) interface{} { return appender.Format })},
+		{LogConsoleCaller, appenderValue(ConsoleLogAppender, func(appender *log.Appender) interface{} { return appender.ShowCaller })},
+		{LogConsoleHostname, appenderValue(ConsoleLogAppender, func(appender *log.Appender) interface{} { return appender.ShowHostname })},
+		{LogFileEnabled, appenderValue(FileLogAppender, func(appender *log.Appender) interface{} { return appender.Enabled })},
+		{LogFilePath, appenderValue(FileLogAppender, func(appender *log.Appender) interface{} { return appender.LogPath })},
+		{LogFileLevel, appenderValue(FileLogAppender, func(appender *log.Appender) interface{} { return appender.LogLevel })},
+		{LogFileFormat, appenderValue(FileLogAppender, func(appender *log.Appender) interface{} { return appender.Format })},
+		{LogFileCaller, appenderValue(FileLogAppender, func(appender *log.Appender) interface{} { return appender.ShowCaller })},
+		{LogFileHostname, appenderValue(FileLogAppender, func(appender *log.Appender) interface{} { return appender.ShowHostname })},
+	}...)
+}
+
+func p2pConfigValues(p2pType string) []configValue {
+	field := func(name string, value func(conf *p2pConf.P2PConfig) interface{}) configValue {
+		return configValue{p2pType + "." + name, func(conf *NodeConfig) interface{} {
+			if p2pConfig, ok := conf.P2PConf[p2pType]; ok && nil != p2pConfig {
+				return value(p2pConfig)
+			}
+			return nil
+		}}
+	}
+	return []configValue{
+		field(P2PAddrBook, func(conf *p2pConf.P2PConfig) interface{} { return conf.AddrBookFilePath }),
+		field(P2PListenAddr, func(conf *p2pConf.P2PConfig) interface{} { return conf.ListenAddress }),
+		field(P2PMaxOut, func(conf *p2pConf.P2PConfig) interface{} { return conf.MaxConnOutBound }),
+		field(P2PMaxIn, func(conf *p2pConf.P2PConfig) interface{} { return conf.MaxConnInBound }),
+		field(P2PPersistendPeers, func(conf *p2pConf.P2PConfig) interface{} { return conf.PersistentPeers }),
+		field(P2PDebug, func(conf *p2pConf.P2PConfig) interface{} { return conf.DebugP2P }),
+		field(P2PDebugServer, func(conf *p2pConf.P2PConfig) interface{} { return conf.DebugServer }),
+		field(P2PDebugAddr, func(conf *p2pConf.P2PConfig) interface{} { return conf.DebugAddr }),
+		field(P2PNAT, func(conf *p2pConf.P2PConfig) interface{} { return conf.NAT }),
+		field(P2PDisableDNSSeed, func(conf *p2pConf.P2PConfig) interface{} { return conf.DisableDNSSeed }),
+		field(P2PDNSSeeds, func(conf *p2pConf.P2PConfig) interface{} { return conf.DNSSeeds }),
+		field(P2PService, func(conf *p2pConf.P2PConfig) interface{} { return conf.Service }),
+	}
+}
+
+func appenderValue(name string, value func(appender *log.Appender) interface{}) func(conf *NodeConfig) interface{} {
+	return func(conf *NodeConfig) interface{} {
+		if appender, ok := conf.Logger.Appenders[name]; ok && nil != appender {
+			return value(appender)
+		}
+		return nil
+	}
+}
+
+func switchVerifySignature(conf *NodeConfig, name string) interface{} {
+	if switchConf, ok := conf.SwitchConf[name]; ok && nil != switchConf {
+		return switchConf.VerifySignature
+	}
+	return nil
+}
+
+// DiffNodeConfig return the keys of justitia.yaml whose values differ between running and loaded config.
+func DiffNodeConfig(running NodeConfig, loaded NodeConfig) []string {
+	var keys []string
+	for _, configValue := range nodeConfigValues {
+		if !reflect.DeepEqual(configValue.value(&running), configValue.value(&loaded)) {
+			keys = append(keys, configValue.key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"github.com/DSiSc/craft/log"
+	p2pConf "github.com/DSiSc/p2p/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func mockDiffConfig() NodeConfig {
+	return NodeConfig{
+		BlockInterval: 2000,
+		P2PConf: map[string]*p2pConf.P2PConfig{
+			BlockP2P: {ListenAddress: "tcp://0.0.0.0:46661"},
+		},
+		Logger: log.Config{
+			Appenders: map[string]*log.Appender{
+				ConsoleLogAppender: {Enabled: true, LogLevel: log.InfoLevel},
+				FileLogAppender:    {Enabled: true, LogLevel: log.InfoLevel, LogPath: "/var/log/justitia/justitia.log"},
+			},
+		},
+	}
+}
+
+func TestDiffNodeConfig(t *testing.T) {
+	assert := assert.New(t)
+	running := mockDiffConfig()
+	assert.Empty(DiffNodeConfig(running, mockDiffConfig()))
+
+	loaded := mockDiffConfig()
+	loaded.BlockInterval = 1000
+	loaded.P2PConf[BlockP2P].PersistentPeers = "tcp://127.0.0.1:46661"
+	loaded.P2PConf[TxP2P] = &p2pConf.P2PConfig{}
+	loaded.Logger.Appenders[FileLogAppender].LogLevel = log.WarnLevel
+	assert.Equal([]string{
+		BlockProducedTimeInterval,
+		BlockP2P + "." + P2PPersistendPeers,
+		TxP2P + "." + P2PAddrBook,
+		TxP2P + "." + P2PListenAddr,
+		TxP2P + "." + P2PMaxOut,
+		TxP2P + "." + P2PMaxIn,
+		TxP2P + "." + P2PPersistendPeers,
+		TxP2P + "." + P2PDebug,
+		TxP2P + "." + P2PDebugServer,
+		TxP2P + "." + P2PDebugAddr,
+		TxP2P + "." + P2PNAT,
+		TxP2P + "." + P2PDisableDNSSeed,
+		TxP2P + "." + P2PDNSSeeds,
+		TxP2P + "." + P2PService,
+		LogFileLevel,
+	}, DiffNodeConfig(running, loaded))
+}
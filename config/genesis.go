@@ -1,55 +1,109 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	types2 "github.com/DSiSc/apigateway/core/types"
 	"github.com/DSiSc/craft/log"
 	"github.com/DSiSc/craft/types"
 	justitiac "github.com/DSiSc/justitia/common"
-	"github.com/DSiSc/justitia/compiler"
 	"github.com/DSiSc/justitia/tools"
+	"github.com/DSiSc/justitia/tools/abi"
 	"github.com/DSiSc/repository"
+	"github.com/DSiSc/validator/tools/account"
 	"github.com/DSiSc/validator/worker"
 	"github.com/DSiSc/validator/worker/common"
+	"github.com/DSiSc/wasm/wasm"
 	"math"
 	"math/big"
 	"os"
 	"path/filepath"
-	"time"
 )
 
 const (
 	GenesisFileName = "genesis.json"
 	InvalidPath     = ""
+	// participates policy which reads consensus participates from genesis file
+	GenesisParticipatesPolicy = "genesis"
 )
 
+// GenesisAccountConfig is an account in genesis file, which is a contract if it has code or contract name.
+// Contract is deployed at addr if it is specified, otherwise at the address created by zero address in the
+// order of contracts without addr. Balance and storage of contract are set after it is deployed.
 type GenesisAccountConfig struct {
-	Addr     string   `json:"addr"     gencodec:"required"`
-	Balance  *big.Int `json:"balance"`
-	Code     string   `json:"code"`
-	Contract string   `json:"contract"`
+	Addr     string   `json:"addr,omitempty"     gencodec:"required"`
+	Balance  *big.Int `json:"balance,omitempty"`
+	Code     string   `json:"code,omitempty"`
+	Contract string   `json:"contract,omitempty"`
+	// arguments of contract constructor, which are abi encoded and appended to code
+	ConstructorArgs []abi.Value `json:"constructorArgs,omitempty"`
+	// storage slots of contract, both slot and value are hex encoded, values of at most 32 bytes are left padded
+	Storage map[string]string `json:"storage,omitempty"`
+	// webassembly module of contract, either hex encoded with 0x prefix or the path of a .wasm file, which is
+	// relative to the directory of genesis file
+	Wasm string `json:"wasm,omitempty"`
+	// hex encoded code kept at addr as it is without running constructor, such as the code exported from a chain
+	RuntimeCode string `json:"runtimeCode,omitempty"`
+	Nonce       uint64 `json:"nonce,omitempty"`
+	// keep account in state though it has no balance, nonce or code, such as empty account exported from a chain
+	KeepEmpty bool `json:"keepEmpty,omitempty"`
+}
+
+// GenesisParticipate is a consensus participate listed in genesis file.
+type GenesisParticipate struct {
+	Addr string `json:"addr"`
+	Id   uint64 `json:"id"`
+	Url  string `json:"url"`
 }
 
 type GenesisBlockConfig struct {
-	Block           *types.Block
+	Block           *GenesisBlockSection
 	GenesisAccounts []GenesisAccountConfig
-	ExtraData       []byte `json:"extra_data"`
+	// extra data of genesis header
+	ExtraData    []byte               `json:"extra_data"`
+	Participates []GenesisParticipate `json:"participates,omitempty"`
 }
 
 // GenesisAccount is the account in genesis block.
 type GenesisAccount struct {
-	Addr     types.Address `json:"addr"     gencodec:"required"`
-	Balance  *big.Int      `json:"balance"`
-	Code     []byte        `json:"code"`
-	Contract string        `json:"contract"`
+	Addr    types.Address `json:"addr"     gencodec:"required"`
+	Balance *big.Int      `json:"balance"`
+	// creation code of contract with constructor arguments, or its runtime code
+	Code     []byte                `json:"code"`
+	Contract string                `json:"contract"`
+	Storage  map[types.Hash][]byte `json:"storage,omitempty"`
+	Nonce    uint64                `json:"nonce,omitempty"`
+	// whether code is runtime code, which is kept at addr as it is
+	Runtime bool `json:"runtime,omitempty"`
+	// whether account is created even if it has no balance or nonce
+	KeepEmpty bool `json:"keepEmpty,omitempty"`
+}
+
+// IsContract report whether account is a contract deployed by genesis block.
+func (account *GenesisAccount) IsContract() bool {
+	return 0 != len(account.Code)
+}
+
+// IsWasm report whether contract is a webassembly module, which is run by wasm vm instead of evm.
+func (account *GenesisAccount) IsWasm() bool {
+	return wasm.IsValidWasmCode(account.Code)
+}
+
+// FixedAddress report whether contract is deployed at the address given in genesis file.
+func (account *GenesisAccount) FixedAddress() bool {
+	return account.IsContract() && (account.Runtime || (types.Address{}) != account.Addr)
 }
 
 // GenesisBlock is the genesis block struct of the chain.
 type GenesisBlock struct {
 	Block           *types.Block
 	GenesisAccounts []GenesisAccount
-	ExtraData       []byte `json:"extra_data"`
+	ExtraData       []byte               `json:"extra_data"`
+	Participates    []GenesisParticipate `json:"participates,omitempty"`
 }
 
 // BuildGenesisBlock build genesis block from the specified config file.
@@ -66,13 +120,8 @@ func GenerateGenesisBlock() (*GenesisBlock, error) {
 }
 
 func genesisFilePath() string {
-	homePath, _ := tools.Home()
-	if tools.PathExists(fmt.Sprintf("%s/.justitia/%s", homePath, GenesisFileName)) {
-		return fmt.Sprintf("%s/.justitia/%s", homePath, GenesisFileName)
-	}
-	goPath := os.Getenv("GOPATH")
-	for _, p := range filepath.SplitList(goPath) {
-		path := filepath.Join(p, fmt.Sprintf("src/github.com/DSiSc/justitia/config/%s", GenesisFileName))
+	for _, dir := range configDirs() {
+		path := filepath.Join(dir, GenesisFileName)
 		if tools.PathExists(path) {
 			return path
 		}
@@ -80,12 +129,12 @@ func genesisFilePath() string {
 	return InvalidPath
 }
 
-// add tx to genesis block
+// add tx to genesis block, which deploy contracts without fixed address.
 func (genesis *GenesisBlock) addTxToGenesisBlock() {
 	var nonce uint64
 	for _, key := range genesis.GenesisAccounts {
-		if 0 != len(key.Code) {
-			tx := types2.NewTransaction(nonce, nil, key.Balance, uint64(0), big.NewInt(0), key.Code, types2.Address{})
+		if key.IsContract() && !key.FixedAddress() {
+			tx := types2.NewTransaction(nonce, nil, big.NewInt(0), uint64(0), big.NewInt(0), key.Code, types2.Address{})
 			genesis.Block.Transactions = append(genesis.Block.Transactions, tx)
 			nonce++
 		}
@@ -94,43 +143,44 @@ func (genesis *GenesisBlock) addTxToGenesisBlock() {
 
 // parse genesis block from config file.
 func buildGenesisFromConfig(genesisPath string) (*GenesisBlock, error) {
-	file, err := os.Open(genesisPath)
-	if err != nil {
-		log.Error("Failed to open genesis file, as: %v", err)
-		return nil, fmt.Errorf("Failed to open genesis file, as: %v ", err)
+	genesis, err := readGenesisConfig(genesisPath)
+	if nil != err {
+		return nil, err
 	}
-	defer file.Close()
+	for index, account := range genesis.GenesisAccounts {
+		if isWasmPath(account.Wasm) && !filepath.IsAbs(account.Wasm) {
+			genesis.GenesisAccounts[index].Wasm = filepath.Join(filepath.Dir(genesisPath), account.Wasm)
+		}
+	}
+	return NewGenesisBlock(genesis)
+}
 
-	genesis := new(GenesisBlockConfig)
-	if err := json.NewDecoder(file).Decode(genesis); err != nil {
-		log.Error("Failed to parse genesis file, as: %v", err)
-		return nil, fmt.Errorf("Failed to parse genesis file, as: %v ", err)
+// NewGenesisBlock build genesis block from the content of genesis file, contracts without code are rejected.
+func NewGenesisBlock(genesis *GenesisBlockConfig) (*GenesisBlock, error) {
+	header, err := newGenesisHeader(genesis)
+	if nil != err {
+		log.Error("Invalid genesis header: %v.", err)
+		return nil, fmt.Errorf("invalid genesis header: %v", err)
 	}
 	genesisBlock := &GenesisBlock{
-		Block:           genesis.Block,
+		Block: &types.Block{
+			Header:       header,
+			Transactions: make([]*types.Transaction, 0),
+		},
 		GenesisAccounts: make([]GenesisAccount, 0),
 		ExtraData:       genesis.ExtraData,
+		Participates:    genesis.Participates,
 	}
-	for _, account := range genesis.GenesisAccounts {
-		contractByteCode := ""
-		genesisAccount := GenesisAccount{
-			Addr:     tools.HexToAddress(account.Addr),
-			Balance:  account.Balance,
-			Contract: account.Contract,
-		}
-		if contractByteCode != account.Code {
-			genesisAccount.Code = tools.Hex2Bytes(account.Code)
-		} else {
-			if contractByteCode != account.Contract {
-				contractByteCode = compiler.SolidityCompile(account.Contract)
-				genesisAccount.Code = tools.Hex2Bytes(contractByteCode)
-			}
+	for index, account := range genesis.GenesisAccounts {
+		genesisAccount, err := newGenesisAccount(account)
+		if nil != err {
+			log.Error("Invalid genesis account %d: %v.", index, err)
+			return nil, fmt.Errorf("invalid genesis account %d: %v", index, err)
 		}
 		genesisBlock.GenesisAccounts = append(genesisBlock.GenesisAccounts, genesisAccount)
 	}
 	genesisBlock.addTxToGenesisBlock()
-	genesisBlock.Block.Header.Timestamp = uint64(time.Date(2018, time.August, 28, 0, 0, 0, 0, time.UTC).Unix())
-	return genesisBlock, err
+	return genesisBlock, nil
 }
 
 // build default genesis block.
@@ -140,7 +190,7 @@ func buildDefaultGenesis() (*GenesisBlock, error) {
 		TxRoot:        types.Hash{},
 		ReceiptsRoot:  types.Hash{},
 		Height:        uint64(0),
-		Timestamp:     uint64(time.Date(2018, time.August, 28, 0, 0, 0, 0, time.UTC).Unix()),
+		Timestamp:     defaultGenesisTimestamp,
 	}
 
 	// genesis block
@@ -164,56 +214,213 @@ func buildDefaultGenesis() (*GenesisBlock, error) {
 	return genesisBlock, nil
 }
 
-func ImportGenesisBlock() {
+// keys of genesis records in repository, which are written when genesis block is imported.
+var (
+	genesisHashKey    = []byte("justitia-genesis-hash")
+	genesisChainIdKey = []byte("justitia-genesis-chain-id")
+	genesisDigestKey  = []byte("justitia-genesis-digest")
+)
+
+// Digest return the sha256 of genesis content, which covers accounts, participates and sign algorithm that
+// header hash of genesis block doesn't cover, as state root is not in header before genesis is imported.
+func (genesis *GenesisBlock) Digest() (types.Hash, error) {
+	type digestAccount struct {
+		GenesisAccount
+		// storage keyed by hex encoded slot, as hash can't be the key of json object
+		Storage map[string][]byte `json:"storage,omitempty"`
+	}
+	accounts := make([]digestAccount, 0, len(genesis.GenesisAccounts))
+	for _, account := range genesis.GenesisAccounts {
+		storage := make(map[string][]byte, len(account.Storage))
+		for slot, value := range account.Storage {
+			storage[hex.EncodeToString(slot[:])] = value
+		}
+		accounts = append(accounts, digestAccount{GenesisAccount: account, Storage: storage})
+	}
+	content, err := json.Marshal(struct {
+		Header          *types.Header
+		GenesisAccounts []digestAccount
+		ExtraData       []byte
+		Participates    []GenesisParticipate
+	}{
+		Header:          genesis.Block.Header,
+		GenesisAccounts: accounts,
+		ExtraData:       genesis.ExtraData,
+		Participates:    genesis.Participates,
+	})
+	if nil != err {
+		return types.Hash{}, fmt.Errorf("encode genesis content failed: %v", err)
+	}
+	return sha256.Sum256(content), nil
+}
+
+// ImportGenesisBlock import genesis block into an empty chain, and record its hash, content digest and chain id
+// in repository. Chain which already has blocks is checked against genesis file instead, and an error is
+// returned if it is another chain or genesis file has been changed.
+func ImportGenesisBlock() error {
 	chain, err := repository.NewLatestStateRepository()
 	if err != nil {
-		panic(fmt.Errorf("failed to create init-state block chain, as: %v", err))
+		log.Error("Create init-state block chain failed with error %v.", err)
+		return fmt.Errorf("failed to create init-state block chain, as: %v", err)
+	}
+
+	genesisBlock, err := GenerateGenesisBlock()
+	if err != nil {
+		log.Error("Get genesis block failed with error %v.", err)
+		return fmt.Errorf("get genesis block failed with error %s", err)
+	}
+	genesisHash := justitiac.HeaderHash(genesisBlock.Block)
+	chainId := genesisBlock.Block.Header.ChainID
+	digest, err := genesisBlock.Digest()
+	if nil != err {
+		log.Error("Digest genesis block failed with error %v.", err)
+		return err
 	}
 
 	currentBlock := chain.GetCurrentBlock()
 	if currentBlock != nil {
 		log.Info("found latest block with height %d from local database, will skip importing genesis block", currentBlock.Header.Height)
-		return
+		if err = checkGenesis(chain, genesisHash, chainId); nil != err {
+			return err
+		}
+		return checkGenesisDigest(chain, digest)
 	}
 
-	genesisBlock, err := GenerateGenesisBlock()
-	if err != nil {
-		panic(fmt.Errorf("get genesis block failed with error %s", err))
+	if err = genesisBlock.checkContracts(); nil != err {
+		log.Error("Check genesis contracts failed with error %v.", err)
+		return err
 	}
-
-	// set balance and record map
+	// set balance and nonce of accounts, those of contracts are set after they are deployed
 	for _, account := range genesisBlock.GenesisAccounts {
-		if nil != account.Balance && account.Balance.Cmp(big.NewInt(0)) == 1 {
+		if account.IsContract() {
+			continue
+		}
+		funded := nil != account.Balance && account.Balance.Cmp(big.NewInt(0)) == 1
+		if funded || 0 != account.Nonce || account.KeepEmpty {
 			chain.CreateAccount(account.Addr)
+		}
+		if funded {
 			chain.SetBalance(account.Addr, account.Balance)
 		}
-		if len(account.Code) != 0 {
-			contractType := justitiac.SystemContractType(account.Contract)
-			if types.InitialContractType == contractType {
-				panic("illegal parameter")
-			}
+		if 0 != account.Nonce {
+			chain.SetNonce(account.Addr, account.Nonce)
 		}
 	}
 	// execute transaction
+	addresses := genesisBlock.contractAddresses()
+	positional := make([]types.Address, 0, len(genesisBlock.Block.Transactions))
+	for index, account := range genesisBlock.GenesisAccounts {
+		if account.IsContract() && !account.FixedAddress() {
+			positional = append(positional, addresses[index])
+		}
+	}
 	for index, tx := range genesisBlock.Block.Transactions {
-		_, _, _, err, addr := worker.ApplyTransaction(genesisBlock.Block.Header.Coinbase, genesisBlock.Block.Header, chain, tx, new(common.GasPool))
+		_, _, _, err, addr := worker.ApplyTransaction(genesisBlock.Block.Header.CoinBase, genesisBlock.Block.Header, chain, tx, new(common.GasPool))
 		if err != nil {
-			panic("apply transaction failed")
+			log.Error("Apply genesis transaction %d failed with error %v.", index, err)
+			return fmt.Errorf("apply genesis transaction %d failed: %v", index, err)
 		}
-
-		//TODO: optimize
-		if index == 4 {
-			chain.SetBalance(addr, big.NewInt(1000000))
+		if addr != positional[index] {
+			log.Error("Genesis transaction %d deployed contract at %x, expect %x.", index, addr, positional[index])
+			return fmt.Errorf("genesis transaction %d deployed contract at %x instead of %x", index, addr, positional[index])
+		}
+	}
+	// deploy contracts at fixed address, and set their initial state
+	for index, account := range genesisBlock.GenesisAccounts {
+		if account.FixedAddress() {
+			if err = deployAtFixedAddress(chain, genesisBlock.Block.Header, account); nil != err {
+				log.Error("Deploy genesis contract failed with error %v.", err)
+				return err
+			}
+		}
+		if account.IsContract() {
+			initContract(chain, addresses[index], account)
 		}
 	}
 	// update block header hash
-	genesisBlock.Block.HeaderHash = justitiac.HeaderHash(genesisBlock.Block)
+	genesisBlock.Block.HeaderHash = genesisHash
 	genesisBlock.Block.Header.StateRoot = chain.IntermediateRoot(false)
 	// write block
 	err = chain.WriteBlock(genesisBlock.Block)
 	if nil != err {
-		panic("import genesis block failed.")
+		log.Error("Write genesis block failed with error %v.", err)
+		return fmt.Errorf("import genesis block failed: %v", err)
+	}
+	if err = recordGenesis(chain, genesisHash, chainId); nil != err {
+		return err
 	}
+	return recordGenesisDigest(chain, digest)
+}
+
+// recordGenesisDigest keep digest of genesis content in repository.
+func recordGenesisDigest(chain *repository.Repository, digest types.Hash) error {
+	if err := chain.Put(genesisDigestKey, digest[:]); nil != err {
+		log.Error("Record genesis digest failed with error %v.", err)
+		return fmt.Errorf("record genesis digest failed: %v", err)
+	}
+	return nil
+}
+
+// checkGenesisDigest compare digest of genesis content recorded in repository with the one of genesis file, so
+// that changes of accounts, contracts, participates or sign algorithm are detected. Chain imported before
+// digest was recorded takes it from genesis file, whose header has been checked.
+func checkGenesisDigest(chain *repository.Repository, digest types.Hash) error {
+	recorded, err := chain.Get(genesisDigestKey)
+	if nil != err || types.HashLength != len(recorded) {
+		log.Warn("Genesis digest of chain is not recorded in repository, take it from genesis file.")
+		return recordGenesisDigest(chain, digest)
+	}
+	if !bytes.Equal(recorded, digest[:]) {
+		log.Error("Digest %x of genesis file differs from %x of chain in repository.", digest, recorded)
+		return fmt.Errorf("genesis digest %x of genesis file differs from %x of the chain in repository, genesis file has been changed after the chain was imported", digest, recorded)
+	}
+	return nil
+}
+
+// recordGenesis keep hash and chain id of genesis block in repository.
+func recordGenesis(chain *repository.Repository, genesisHash types.Hash, chainId uint64) error {
+	encodedChainId := make([]byte, 8)
+	binary.BigEndian.PutUint64(encodedChainId, chainId)
+	if err := chain.Put(genesisHashKey, genesisHash[:]); nil != err {
+		log.Error("Record genesis hash failed with error %v.", err)
+		return fmt.Errorf("record genesis hash failed: %v", err)
+	}
+	if err := chain.Put(genesisChainIdKey, encodedChainId); nil != err {
+		log.Error("Record chain id failed with error %v.", err)
+		return fmt.Errorf("record chain id failed: %v", err)
+	}
+	return nil
+}
+
+// checkGenesis compare genesis hash and chain id recorded in repository with the ones of genesis file. Chain
+// imported before they were recorded takes them from its block 0.
+func checkGenesis(chain *repository.Repository, genesisHash types.Hash, chainId uint64) error {
+	recordedHash, hashErr := chain.Get(genesisHashKey)
+	encodedChainId, chainIdErr := chain.Get(genesisChainIdKey)
+	if nil != hashErr || nil != chainIdErr || types.HashLength != len(recordedHash) || 8 != len(encodedChainId) {
+		block, err := chain.GetBlockByHeight(0)
+		if nil != err {
+			log.Error("Read genesis block from repository failed with error %v.", err)
+			return fmt.Errorf("read genesis block from repository failed: %v", err)
+		}
+		log.Warn("Genesis of chain is not recorded in repository, take it from block 0.")
+		blockHash := justitiac.HeaderHash(block)
+		if err := recordGenesis(chain, blockHash, block.Header.ChainID); nil != err {
+			return err
+		}
+		recordedHash = blockHash[:]
+		encodedChainId = make([]byte, 8)
+		binary.BigEndian.PutUint64(encodedChainId, block.Header.ChainID)
+	}
+	if recordedChainId := binary.BigEndian.Uint64(encodedChainId); recordedChainId != chainId {
+		log.Error("Chain id %d in genesis file differs from %d of chain in repository.", chainId, recordedChainId)
+		return fmt.Errorf("chain id %d in genesis file differs from %d of the chain in repository, refuse to start on another chain", chainId, recordedChainId)
+	}
+	if !bytes.Equal(recordedHash, genesisHash[:]) {
+		log.Error("Genesis hash %x of genesis file differs from %x of chain in repository.", genesisHash, recordedHash)
+		return fmt.Errorf("genesis hash %x of genesis file differs from %x of the chain in repository, refuse to start on another chain", genesisHash, recordedHash)
+	}
+	return nil
 }
 
 func GetChainIdFromConfig() (uint64, error) {
@@ -222,12 +429,49 @@ func GetChainIdFromConfig() (uint64, error) {
 		log.Info("GenesisPath is invalid, return the default chainId")
 		return 0, nil
 	}
+	genesis, err := readGenesisConfig(genesisPath)
+	if nil != err {
+		return 0, err
+	}
+	if nil == genesis.Block || nil == genesis.Block.Header {
+		return 0, nil
+	}
+	return genesis.Block.Header.ChainID, nil
+}
+
+// GetParticipatesFromGenesis return the consensus participates listed in genesis file.
+func GetParticipatesFromGenesis() ([]account.Account, error) {
+	var genesisPath = genesisFilePath()
+	if InvalidPath == genesisPath {
+		log.Error("Genesis file not found.")
+		return nil, fmt.Errorf("genesis file not found")
+	}
+	genesis, err := readGenesisConfig(genesisPath)
+	if nil != err {
+		return nil, err
+	}
+	if 0 == len(genesis.Participates) {
+		return nil, fmt.Errorf("no participates in genesis file %s", genesisPath)
+	}
+	participates := make([]account.Account, 0, len(genesis.Participates))
+	for _, participate := range genesis.Participates {
+		participates = append(participates, account.Account{
+			Address: tools.HexToAddress(participate.Addr),
+			Extension: account.AccountExtension{
+				Id:  participate.Id,
+				Url: participate.Url,
+			},
+		})
+	}
+	return participates, nil
+}
 
+func readGenesisConfig(genesisPath string) (*GenesisBlockConfig, error) {
 	//Open genesisFile by the path
 	file, err := os.Open(genesisPath)
 	if err != nil {
 		log.Error("Failed to open genesis file, as: %v", err)
-		return 0, fmt.Errorf("Failed to open genesis file, as: %v ", err)
+		return nil, fmt.Errorf("Failed to open genesis file, as: %v ", err)
 	}
 	defer file.Close()
 
@@ -235,9 +479,7 @@ func GetChainIdFromConfig() (uint64, error) {
 	genesis := new(GenesisBlockConfig)
 	if err := json.NewDecoder(file).Decode(genesis); err != nil {
 		log.Error("Failed to parse genesis file, as: %v", err)
-		return 0, fmt.Errorf("Failed to parse genesis file, as: %v ", err)
+		return nil, fmt.Errorf("Failed to parse genesis file, as: %v ", err)
 	}
-	chainId := genesis.Block.Header.ChainID
-
-	return chainId, nil
+	return genesis, nil
 }
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DSiSc/craft/types"
+	justitiac "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/compiler"
+	"regexp"
+	"strings"
+)
+
+// SystemContracts are the contracts which could be deployed by genesis block, in the order they are deployed.
+var SystemContracts = []string{
+	types.JustitiaRightToken,
+	types.JustitiaVoting,
+	types.JustitiaWhiteList,
+	types.JustitiaMetaData,
+	types.JustitiaCrossFundsPool,
+}
+
+var hexAddressRegexp = regexp.MustCompile(`^(0x|0X)?[0-9a-fA-F]{40}$`)
+
+// GenesisSpec describe the genesis file to be built.
+type GenesisSpec struct {
+	ChainId uint64
+	// funded accounts, whose addresses are hex encoded
+	Accounts []GenesisAccountConfig
+	// consensus participates
+	Participates []GenesisParticipate
+	// names of system contracts
+	Contracts []string
+	// deploy the prebuilt code of system contracts in default genesis file, instead of compiling them
+	Prebuilt bool
+}
+
+// NewGenesisConfig build the content of genesis file from spec. Addresses are written in lower case with 0x prefix,
+// and system contracts are deployed with their code in the order of SystemContracts. Contracts are compiled
+// through compiler, those without source in compiler, or without prebuilt code if Prebuilt is set, are rejected.
+func NewGenesisConfig(spec GenesisSpec) (*GenesisBlockConfig, error) {
+	genesis := &GenesisBlockConfig{
+		Block: &GenesisBlockSection{
+			Header: &GenesisHeaderConfig{
+				ChainID: spec.ChainId,
+			},
+		},
+		GenesisAccounts: make([]GenesisAccountConfig, 0),
+	}
+	funded := make(map[string]bool)
+	for _, account := range spec.Accounts {
+		address, err := normalizeAddress(account.Addr)
+		if nil != err {
+			return nil, err
+		}
+		if funded[address] {
+			return nil, fmt.Errorf("account %s is funded twice", address)
+		}
+		if nil == account.Balance || account.Balance.Sign() < 0 {
+			return nil, fmt.Errorf("invalid balance of account %s", address)
+		}
+		funded[address] = true
+		genesis.GenesisAccounts = append(genesis.GenesisAccounts, GenesisAccountConfig{
+			Addr:    address,
+			Balance: account.Balance,
+		})
+	}
+	ids := make(map[uint64]bool)
+	for _, participate := range spec.Participates {
+		address, err := normalizeAddress(participate.Addr)
+		if nil != err {
+			return nil, err
+		}
+		if ids[participate.Id] {
+			return nil, fmt.Errorf("participate id %d is used twice", participate.Id)
+		}
+		if justitiac.BlankString == participate.Url {
+			return nil, fmt.Errorf("url of participate %s not specified", address)
+		}
+		ids[participate.Id] = true
+		genesis.Participates = append(genesis.Participates, GenesisParticipate{
+			Addr: address,
+			Id:   participate.Id,
+			Url:  participate.Url,
+		})
+	}
+	contracts := make(map[string]bool)
+	for _, name := range spec.Contracts {
+		if types.InitialContractType == justitiac.SystemContractType(name) {
+			return nil, fmt.Errorf("unknown system contract %s, which should be one of %v", name, SystemContracts)
+		}
+		if spec.Prebuilt {
+			if _, err := prebuiltContractCode(name); nil != err {
+				return nil, fmt.Errorf("%v, which should be one of %v", err, PrebuiltContracts())
+			}
+		} else if !compiler.HasContract(name) {
+			return nil, fmt.Errorf("source of system contract %s is not in compiler, which has %v", name, CompiledContracts())
+		}
+		if contracts[name] {
+			return nil, fmt.Errorf("system contract %s is deployed twice", name)
+		}
+		contracts[name] = true
+	}
+	for _, name := range SystemContracts {
+		if !contracts[name] {
+			continue
+		}
+		code, err := SystemContractCode(name, spec.Prebuilt)
+		if nil != err {
+			return nil, err
+		}
+		genesis.GenesisAccounts = append(genesis.GenesisAccounts, GenesisAccountConfig{
+			Code:     code,
+			Contract: name,
+		})
+	}
+	return genesis, nil
+}
+
+func normalizeAddress(address string) (string, error) {
+	if !hexAddressRegexp.MatchString(address) {
+		return justitiac.BlankString, fmt.Errorf("invalid address %s, which should be 40 hex characters", address)
+	}
+	return "0x" + strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X")), nil
+}
+
+// SystemContractCode return the hex encoded code of a system contract compiled through compiler, which fails if
+// solc is not installed. With prebuilt, the code in default genesis file is returned instead.
+func SystemContractCode(name string, prebuilt bool) (string, error) {
+	if prebuilt {
+		return prebuiltContractCode(name)
+	}
+	if !compiler.HasContract(name) {
+		return justitiac.BlankString, fmt.Errorf("source of system contract %s is not in compiler", name)
+	}
+	if _, err := compiler.SolidityVersion(); nil != err {
+		return justitiac.BlankString, fmt.Errorf("solc is not available to compile contract %s: %v", name, err)
+	}
+	return compiler.CompileContract(name)
+}
+
+// CompiledContracts return the system contracts whose source is in compiler, which genesis new compiles.
+func CompiledContracts() []string {
+	contracts := make([]string, 0, len(SystemContracts))
+	for _, name := range SystemContracts {
+		if compiler.HasContract(name) {
+			contracts = append(contracts, name)
+		}
+	}
+	return contracts
+}
+
+// PrebuiltContracts return the system contracts with prebuilt code, which genesis new deploys with --prebuilt.
+func PrebuiltContracts() []string {
+	contracts := make([]string, 0, len(SystemContracts))
+	for _, name := range SystemContracts {
+		if _, err := prebuiltContractCode(name); nil == err {
+			contracts = append(contracts, name)
+		}
+	}
+	return contracts
+}
+
+// prebuiltContractCode return the code of contract in default genesis file.
+func prebuiltContractCode(name string) (string, error) {
+	var genesis GenesisBlockConfig
+	if err := json.Unmarshal(DefaultGenesisFile, &genesis); nil != err {
+		return justitiac.BlankString, fmt.Errorf("parse default genesis file failed: %v", err)
+	}
+	for _, account := range genesis.GenesisAccounts {
+		if name == account.Contract && justitiac.BlankString != account.Code {
+			return account.Code, nil
+		}
+	}
+	return justitiac.BlankString, fmt.Errorf("system contract %s has no prebuilt code", name)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"github.com/DSiSc/justitia/compiler"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+func TestSystemContractCode(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"JustitiaRight", "Voting", "WhiteList", "MetaData"}, PrebuiltContracts())
+	assert.Equal([]string{"JustitiaRight", "Voting"}, CompiledContracts())
+	_, err := SystemContractCode("CrossFundsPool", true)
+	assert.NotNil(err)
+	_, err = SystemContractCode("WhiteList", false)
+	assert.NotNil(err)
+	if _, err = compiler.SolidityVersion(); nil != err {
+		_, err = SystemContractCode("Voting", false)
+		assert.NotNil(err)
+	}
+	code, err := SystemContractCode("Voting", true)
+	assert.Nil(err)
+	assert.NotEmpty(code)
+}
+
+func TestNewGenesisConfig(t *testing.T) {
+	assert := assert.New(t)
+	spec := GenesisSpec{
+		ChainId:  3,
+		Accounts: []GenesisAccountConfig{{Addr: "A94F5374FCE5EDBC8E2A8697C15331677E6EBF0B", Balance: big.NewInt(10)}},
+		Participates: []GenesisParticipate{
+			{Addr: "0x333c3310824b7c685133f2bedb2ca4b8b4df633d", Id: 0, Url: "127.0.0.1:8080"},
+		},
+		Contracts: []string{"MetaData"},
+		Prebuilt:  true,
+	}
+	genesis, err := NewGenesisConfig(spec)
+	assert.Nil(err)
+	assert.Equal(uint64(3), genesis.Block.Header.ChainID)
+	assert.Equal("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", genesis.GenesisAccounts[0].Addr)
+	code, err := SystemContractCode("MetaData", true)
+	assert.Nil(err)
+	assert.Equal(GenesisAccountConfig{Code: code, Contract: "MetaData"}, genesis.GenesisAccounts[1])
+
+	invalid := spec
+	invalid.Contracts = []string{"CrossFundsPool"}
+	_, err = NewGenesisConfig(invalid)
+	assert.Equal(fmt.Errorf("system contract CrossFundsPool has no prebuilt code, which should be one of %v", PrebuiltContracts()), err)
+	invalid.Prebuilt = false
+	_, err = NewGenesisConfig(invalid)
+	assert.Equal(fmt.Errorf("source of system contract CrossFundsPool is not in compiler, which has %v", CompiledContracts()), err)
+	invalid.Contracts = []string{"MetaData"}
+	_, err = NewGenesisConfig(invalid)
+	assert.NotNil(err)
+	invalid.Prebuilt = true
+	invalid.Contracts = []string{"MetaData", "MetaData"}
+	_, err = NewGenesisConfig(invalid)
+	assert.NotNil(err)
+
+	invalid = spec
+	invalid.Participates = append(invalid.Participates, GenesisParticipate{Addr: "0x343c3310824b7c685133f2bedb2ca4b8b4df633d", Id: 0, Url: "127.0.0.1:8081"})
+	_, err = NewGenesisConfig(invalid)
+	assert.NotNil(err)
+
+	invalid = spec
+	invalid.Accounts = []GenesisAccountConfig{{Addr: "0xa94f", Balance: big.NewInt(10)}}
+	_, err = NewGenesisConfig(invalid)
+	assert.NotNil(err)
+	invalid.Accounts = []GenesisAccountConfig{{Addr: "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", Balance: big.NewInt(-1)}}
+	_, err = NewGenesisConfig(invalid)
+	assert.NotNil(err)
+}
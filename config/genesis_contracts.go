@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/crypto-suite/crypto"
+	evmNg "github.com/DSiSc/evm-NG"
+	justitiac "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/DSiSc/justitia/tools/abi"
+	"github.com/DSiSc/repository"
+	evmCommon "github.com/DSiSc/validator/common"
+	"github.com/DSiSc/wasm/exec"
+	"github.com/DSiSc/wasm/validate"
+	"github.com/DSiSc/wasm/wasm"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// newGenesisAccount parse an account in genesis file, contract without code is compiled from its source.
+func newGenesisAccount(account GenesisAccountConfig) (GenesisAccount, error) {
+	genesisAccount := GenesisAccount{
+		Addr:      tools.HexToAddress(account.Addr),
+		Balance:   account.Balance,
+		Contract:  account.Contract,
+		Nonce:     account.Nonce,
+		KeepEmpty: account.KeepEmpty,
+	}
+	name := account.Contract
+	if justitiac.BlankString == name {
+		name = account.Addr
+	}
+	codes := 0
+	for _, code := range []string{account.Code, account.Wasm, account.RuntimeCode} {
+		if justitiac.BlankString != code {
+			codes++
+		}
+	}
+	if codes > 1 {
+		return genesisAccount, fmt.Errorf("only one of code, wasm and runtime code of %s could be specified", name)
+	}
+	switch {
+	case justitiac.BlankString != account.Wasm:
+		if 0 != len(account.ConstructorArgs) {
+			return genesisAccount, fmt.Errorf("wasm contract %s has no constructor", name)
+		}
+		code, err := readWasmModule(account.Wasm)
+		if nil != err {
+			return genesisAccount, fmt.Errorf("invalid wasm module of %s: %v", name, err)
+		}
+		genesisAccount.Code = code
+	case justitiac.BlankString != account.RuntimeCode:
+		if (types.Address{}) == genesisAccount.Addr {
+			return genesisAccount, fmt.Errorf("addr of runtime code of %s not specified", name)
+		}
+		if 0 != len(account.ConstructorArgs) {
+			return genesisAccount, fmt.Errorf("runtime code of %s has no constructor", name)
+		}
+		code, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(account.RuntimeCode, "0x"), "0X"))
+		if nil != err {
+			return genesisAccount, fmt.Errorf("invalid runtime code of %s: %v", name, err)
+		}
+		genesisAccount.Code = code
+		genesisAccount.Runtime = true
+	case justitiac.BlankString != account.Code:
+		genesisAccount.Code = tools.Hex2Bytes(account.Code)
+	case justitiac.BlankString != account.Contract:
+		// compiling at start would make importing genesis depend on solc, and the code on its version
+		return genesisAccount, fmt.Errorf("code of contract %s not specified, which genesis new writes", name)
+	}
+	if !genesisAccount.IsContract() {
+		if 0 != len(account.ConstructorArgs) || 0 != len(account.Storage) {
+			return genesisAccount, fmt.Errorf("constructor arguments and storage are only accepted by contract")
+		}
+		return genesisAccount, nil
+	}
+	if account.KeepEmpty {
+		return genesisAccount, fmt.Errorf("keepEmpty is not accepted by contract %s", name)
+	}
+	// contracts copied from a chain keep their code only, others are system contracts known by name
+	if justitiac.BlankString == account.Contract && !genesisAccount.Runtime {
+		return genesisAccount, fmt.Errorf("contract name not specified")
+	}
+	args, err := abi.Encode(account.ConstructorArgs)
+	if nil != err {
+		return genesisAccount, fmt.Errorf("encode constructor arguments of %s failed: %v", name, err)
+	}
+	genesisAccount.Code = append(genesisAccount.Code, args...)
+	if 0 != len(account.Storage) {
+		genesisAccount.Storage = make(map[types.Hash][]byte)
+		for slot, value := range account.Storage {
+			key, err := parseStorageWord(slot)
+			if nil != err {
+				return genesisAccount, fmt.Errorf("invalid storage slot %s of %s: %v", slot, name, err)
+			}
+			if genesisAccount.Storage[key], err = parseStorageValue(value); nil != err {
+				return genesisAccount, fmt.Errorf("invalid storage value of slot %s of %s: %v", slot, name, err)
+			}
+		}
+	}
+	return genesisAccount, nil
+}
+
+// isWasmPath report whether wasm field of genesis account is a file path rather than hex encoded module.
+func isWasmPath(module string) bool {
+	return justitiac.BlankString != module && !strings.HasPrefix(module, "0x") && !strings.HasPrefix(module, "0X")
+}
+
+// readWasmModule load a webassembly module from hex or file, and verify it as wasm vm does before running it.
+func readWasmModule(module string) ([]byte, error) {
+	var code []byte
+	if isWasmPath(module) {
+		content, err := ioutil.ReadFile(module)
+		if nil != err {
+			return nil, err
+		}
+		code = content
+	} else {
+		decoded, err := hex.DecodeString(module[2:])
+		if nil != err {
+			return nil, err
+		}
+		code = decoded
+	}
+	if !wasm.IsValidWasmCode(code) {
+		return nil, fmt.Errorf("not a webassembly module")
+	}
+	parsed, err := wasm.ReadModule(bytes.NewReader(code), exec.NativeResolve)
+	if nil != err {
+		return nil, err
+	}
+	if err = validate.VerifyModule(parsed); nil != err {
+		return nil, err
+	}
+	return code, nil
+}
+
+// decodeStorageHex decode a hex string with optional 0x prefix, whose leading zero could be omitted.
+func decodeStorageHex(value string) ([]byte, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	if 1 == len(trimmed)%2 {
+		trimmed = "0" + trimmed
+	}
+	return hex.DecodeString(trimmed)
+}
+
+// parseStorageValue decode a storage value, which is left padded to 32 bytes if it is shorter, as values set by
+// evm are. Longer values are kept as they are.
+func parseStorageValue(value string) ([]byte, error) {
+	raw, err := decodeStorageHex(value)
+	if nil != err || len(raw) > types.HashLength {
+		return raw, err
+	}
+	word := make([]byte, types.HashLength)
+	copy(word[types.HashLength-len(raw):], raw)
+	return word, nil
+}
+
+// parseStorageWord decode a hex string of at most 32 bytes, which is left padded with zero.
+func parseStorageWord(word string) (types.Hash, error) {
+	var hash types.Hash
+	raw, err := decodeStorageHex(word)
+	if nil != err {
+		return hash, err
+	}
+	if len(raw) > types.HashLength {
+		return hash, fmt.Errorf("longer than %d bytes", types.HashLength)
+	}
+	copy(hash[types.HashLength-len(raw):], raw)
+	return hash, nil
+}
+
+// contractAddresses return the addresses of contracts by their indexes in genesis accounts. Contracts without
+// fixed address are created by genesis transactions from zero address, so their addresses follow their order.
+func (genesis *GenesisBlock) contractAddresses() map[int]types.Address {
+	addresses := make(map[int]types.Address)
+	var nonce uint64
+	for index, account := range genesis.GenesisAccounts {
+		if !account.IsContract() {
+			continue
+		}
+		if account.FixedAddress() {
+			addresses[index] = account.Addr
+		} else {
+			addresses[index] = crypto.CreateAddress(types.Address{}, nonce)
+			nonce++
+		}
+	}
+	return addresses
+}
+
+// SystemContracts return the addresses of named contracts deployed by genesis block.
+func (genesis *GenesisBlock) SystemContracts() map[string]types.Address {
+	contracts := make(map[string]types.Address)
+	for index, address := range genesis.contractAddresses() {
+		if name := genesis.GenesisAccounts[index].Contract; justitiac.BlankString != name {
+			contracts[name] = address
+		}
+	}
+	return contracts
+}
+
+// checkContracts make sure contracts in genesis are system contracts, and each of them is deployed once.
+// Runtime code without name is not a system contract.
+func (genesis *GenesisBlock) checkContracts() error {
+	names := make(map[string]bool)
+	for _, account := range genesis.GenesisAccounts {
+		if !account.IsContract() || (account.Runtime && justitiac.BlankString == account.Contract) {
+			continue
+		}
+		if types.InitialContractType == justitiac.SystemContractType(account.Contract) {
+			return fmt.Errorf("illegal system contract %s in genesis", account.Contract)
+		}
+		if names[account.Contract] {
+			return fmt.Errorf("system contract %s is deployed twice in genesis", account.Contract)
+		}
+		names[account.Contract] = true
+	}
+	return nil
+}
+
+// deployAtFixedAddress run creation code of contract at its fixed address from zero address, as genesis
+// transactions do, and keep the returned runtime code as its code. Wasm module is deployed as it is.
+func deployAtFixedAddress(chain *repository.Repository, header *types.Header, account GenesisAccount) error {
+	if 0 != chain.GetCodeSize(account.Addr) {
+		return fmt.Errorf("address %x of contract %s already has code", account.Addr, account.Contract)
+	}
+	if account.Runtime || account.IsWasm() {
+		// wasm contract has no constructor, its module is kept as code directly, as wasm vm creates it.
+		chain.CreateAccount(account.Addr)
+		chain.SetCode(account.Addr, account.Code)
+		return nil
+	}
+	deployer := types.Address{}
+	tx := types.Transaction{
+		Data: types.TxData{
+			From:   &deployer,
+			Price:  big.NewInt(0),
+			Amount: big.NewInt(0),
+		},
+	}
+	chain.CreateAccount(account.Addr)
+	chain.SetCode(account.Addr, account.Code)
+	evm := evmNg.NewEVM(evmNg.NewEVMContext(tx, header, chain, header.CoinBase), chain)
+	code, _, err := evm.Call(evmCommon.NewRefAddress(deployer), account.Addr, nil, math.MaxUint64, big.NewInt(0))
+	if nil != err {
+		return fmt.Errorf("deploy contract %s at %x failed: %v", account.Contract, account.Addr, err)
+	}
+	chain.SetCode(account.Addr, code)
+	return nil
+}
+
+// initContract set balance, nonce and storage of a deployed contract.
+func initContract(chain *repository.Repository, address types.Address, account GenesisAccount) {
+	if nil != account.Balance && account.Balance.Sign() > 0 {
+		chain.SetBalance(address, account.Balance)
+	}
+	if 0 != account.Nonce {
+		chain.SetNonce(address, account.Nonce)
+	}
+	for slot, value := range account.Storage {
+		chain.SetState(address, slot, value)
+	}
+	log.Info("Contract %s deployed at %x.", account.Contract, address)
+}
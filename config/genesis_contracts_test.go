@@ -0,0 +1,178 @@
+package config
+
+import (
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/crypto-suite/crypto"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/DSiSc/justitia/tools/abi"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/repository"
+	repositoryConfig "github.com/DSiSc/repository/config"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	// runtime code returning slot 1
+	loadSlotCode = "60015460005260206000f3"
+	// creation code storing its constructor argument in slot 1, then returning loadSlotCode
+	storeArgCode = "6020803803600039600051600155600b8060196000396000f3" + loadSlotCode
+	// wasm module exporting main which returns 42
+	mainWasm = "0061736d010000000105016000017f03020100070801046d61696e00000a07010500412a0f0b"
+	// wasm module whose main adds two i32 from an empty stack
+	invalidWasm = "0061736d010000000105016000017f03020100070801046d61696e00000a050103006a0b"
+)
+
+func TestContractAddresses(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(tools.HexToAddress(types.JustiitaContractDefaultAddress), crypto.CreateAddress(types.Address{}, 0))
+
+	fixed := tools.HexToAddress("0x00000000000000000000000000000000000000aa")
+	genesis := &GenesisBlock{GenesisAccounts: []GenesisAccount{
+		{Addr: tools.HexToAddress("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"), Balance: big.NewInt(1)},
+		{Code: []byte{0x00}, Contract: types.JustitiaRightToken},
+		{Addr: fixed, Code: []byte{0x00}, Contract: types.JustitiaMetaData},
+		{Code: []byte{0x00}, Contract: types.JustitiaVoting},
+	}}
+	assert.Equal(map[string]types.Address{
+		types.JustitiaRightToken: crypto.CreateAddress(types.Address{}, 0),
+		types.JustitiaMetaData:   fixed,
+		types.JustitiaVoting:     crypto.CreateAddress(types.Address{}, 1),
+	}, genesis.SystemContracts())
+}
+
+func TestNewGenesisAccount(t *testing.T) {
+	assert := assert.New(t)
+	account, err := newGenesisAccount(GenesisAccountConfig{
+		Code:            "6000",
+		Contract:        types.JustitiaMetaData,
+		ConstructorArgs: []abi.Value{{Type: "uint8", Value: []byte("1")}},
+		Storage:         map[string]string{"0x1": "0x0102"},
+	})
+	assert.Nil(err)
+	assert.Equal(34, len(account.Code))
+	assert.Equal(append(make([]byte, 30), 1, 2), account.Storage[types.Hash{31: 1}])
+
+	_, err = newGenesisAccount(GenesisAccountConfig{Addr: "0x00000000000000000000000000000000000000aa", Storage: map[string]string{"0x1": "0x1"}})
+	assert.NotNil(err)
+	// contract named without code is not compiled at start
+	_, err = newGenesisAccount(GenesisAccountConfig{Contract: types.JustitiaVoting})
+	assert.NotNil(err)
+	_, err = newGenesisAccount(GenesisAccountConfig{Code: "6000", Contract: types.JustitiaMetaData, Storage: map[string]string{"0xzz": "0x1"}})
+	assert.NotNil(err)
+	_, err = newGenesisAccount(GenesisAccountConfig{Code: "6000", Contract: types.JustitiaMetaData, Storage: map[string]string{"0x" + strings.Repeat("01", 33): "0x1"}})
+	assert.NotNil(err)
+	// value longer than a word is kept as it is
+	account, err = newGenesisAccount(GenesisAccountConfig{Code: "6000", Contract: types.JustitiaMetaData, Storage: map[string]string{"0x1": "0x" + strings.Repeat("01", 33)}})
+	assert.Nil(err)
+	assert.Equal(33, len(account.Storage[types.Hash{31: 1}]))
+
+	// runtime code is kept at its addr, which could be a contract without name
+	account, err = newGenesisAccount(GenesisAccountConfig{Addr: "0x00000000000000000000000000000000000000aa", RuntimeCode: loadSlotCode, Nonce: 1})
+	assert.Nil(err)
+	assert.True(account.Runtime)
+	assert.True(account.FixedAddress())
+	assert.Equal(uint64(1), account.Nonce)
+	_, err = newGenesisAccount(GenesisAccountConfig{RuntimeCode: loadSlotCode})
+	assert.NotNil(err)
+	_, err = newGenesisAccount(GenesisAccountConfig{Addr: "0x00000000000000000000000000000000000000aa", RuntimeCode: loadSlotCode, Code: storeArgCode})
+	assert.NotNil(err)
+}
+
+func TestImportGenesisBlockContractState(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	SetHome(dir)
+	defer SetHome("")
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, GenesisFileName), []byte(`{
+		"Block": {"Header": {"chainId": 1}},
+		"GenesisAccounts": [
+			{"code": "`+storeArgCode+`", "contract": "WhiteList", "constructorArgs": [{"type": "uint256", "value": 7}]},
+			{"addr": "0x00000000000000000000000000000000000000aa", "balance": 100, "code": "`+storeArgCode+`",
+			 "contract": "CrossFundsPool", "constructorArgs": [{"type": "uint256", "value": "0x2a"}],
+			 "storage": {"0x0": "0x05"}}
+		]}`), 0644))
+	assert.Nil(repository.InitRepository(repositoryConfig.RepositoryConfig{PluginName: repository.PLUGIN_MEMDB}, events.NewEvent()))
+	assert.Nil(ImportGenesisBlock())
+	chain, err := repository.NewLatestStateRepository()
+	assert.Nil(err)
+
+	positional := crypto.CreateAddress(types.Address{}, 0)
+	assert.Equal(types.Hash{31: 7}, chain.GetHashTypeState(positional, types.Hash{31: 1}))
+	fixed := tools.HexToAddress("0x00000000000000000000000000000000000000aa")
+	assert.Equal(tools.Hex2Bytes(loadSlotCode), chain.GetCode(fixed))
+	assert.Equal(types.Hash{31: 0x2a}, chain.GetHashTypeState(fixed, types.Hash{31: 1}))
+	assert.Equal(types.Hash{31: 5}, chain.GetHashTypeState(fixed, types.Hash{}))
+	assert.Equal(big.NewInt(100), chain.GetBalance(fixed))
+
+	genesis, err := GenerateGenesisBlock()
+	assert.Nil(err)
+	assert.Equal(map[string]types.Address{
+		types.JustitiaWhiteList:      positional,
+		types.JustitiaCrossFundsPool: fixed,
+	}, genesis.SystemContracts())
+}
+
+func TestReadWasmModule(t *testing.T) {
+	assert := assert.New(t)
+	code, err := readWasmModule("0x" + mainWasm)
+	assert.Nil(err)
+	assert.Equal(tools.Hex2Bytes(mainWasm), code)
+
+	dir, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "main.wasm")
+	assert.Nil(ioutil.WriteFile(path, tools.Hex2Bytes(mainWasm), 0644))
+	code, err = readWasmModule(path)
+	assert.Nil(err)
+	assert.Equal(tools.Hex2Bytes(mainWasm), code)
+
+	_, err = readWasmModule("0x" + invalidWasm)
+	assert.NotNil(err)
+	_, err = readWasmModule("0x" + storeArgCode)
+	assert.NotNil(err)
+	_, err = readWasmModule(filepath.Join(dir, "absent.wasm"))
+	assert.NotNil(err)
+	_, err = newGenesisAccount(GenesisAccountConfig{
+		Contract:        types.JustitiaMetaData,
+		Wasm:            "0x" + mainWasm,
+		ConstructorArgs: []abi.Value{{Type: "uint8", Value: []byte("1")}},
+	})
+	assert.NotNil(err)
+}
+
+func TestImportGenesisBlockWasm(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	SetHome(dir)
+	defer SetHome("")
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "main.wasm"), tools.Hex2Bytes(mainWasm), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, GenesisFileName), []byte(`{
+		"Block": {"Header": {"chainId": 1}},
+		"GenesisAccounts": [
+			{"code": "`+storeArgCode+`", "contract": "WhiteList", "constructorArgs": [{"type": "uint256", "value": 7}]},
+			{"wasm": "main.wasm", "contract": "MetaData"},
+			{"addr": "0x00000000000000000000000000000000000000aa", "wasm": "0x`+mainWasm+`", "contract": "CrossFundsPool",
+			 "storage": {"0x0": "0x05"}}
+		]}`), 0644))
+	assert.Nil(repository.InitRepository(repositoryConfig.RepositoryConfig{PluginName: repository.PLUGIN_MEMDB}, events.NewEvent()))
+	assert.Nil(ImportGenesisBlock())
+	chain, err := repository.NewLatestStateRepository()
+	assert.Nil(err)
+
+	assert.Equal(types.Hash{31: 7}, chain.GetHashTypeState(crypto.CreateAddress(types.Address{}, 0), types.Hash{31: 1}))
+	assert.Equal(tools.Hex2Bytes(mainWasm), chain.GetCode(crypto.CreateAddress(types.Address{}, 1)))
+	fixed := tools.HexToAddress("0x00000000000000000000000000000000000000aa")
+	assert.Equal(tools.Hex2Bytes(mainWasm), chain.GetCode(fixed))
+	assert.Equal(types.Hash{31: 5}, chain.GetHashTypeState(fixed, types.Hash{}))
+}
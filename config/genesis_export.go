@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"github.com/DSiSc/blockstore"
+	blkconf "github.com/DSiSc/blockstore/config"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	justitiac "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/DSiSc/repository"
+	repositoryConfig "github.com/DSiSc/repository/config"
+	"github.com/DSiSc/statedb-NG"
+	"github.com/DSiSc/statedb-NG/common/rlp"
+	"github.com/DSiSc/statedb-NG/ethdb/leveldb"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// LatestHeight ask ExportGenesis to export the state of current block.
+const LatestHeight = math.MaxUint64
+
+// GenesisExport report where an exported genesis comes from.
+type GenesisExport struct {
+	Height    uint64
+	BlockHash types.Hash
+	StateRoot types.Hash
+	// numbers of exported accounts, contracts and empty accounts among them
+	Accounts  int
+	Contracts int
+	Empty     int
+}
+
+// ExportGenesis build a genesis file from the state of block at height, whose accounts keep their balance, nonce,
+// code and storage, so importing it reproduces the state root. Chain id, signature algorithm, participates and
+// extra data are taken from genesis file, and timestamp from the block. Databases of repository are opened by
+// it, so node should be stopped first.
+func ExportGenesis(conf repositoryConfig.RepositoryConfig, height uint64) (*GenesisBlockConfig, *GenesisExport, error) {
+	if repository.PLUGIN_LEVELDB != conf.PluginName {
+		return nil, nil, fmt.Errorf("repository plugin %s keeps no state after node stopped", conf.PluginName)
+	}
+	store, err := blockstore.NewBlockStore(&blkconf.BlockStoreConfig{
+		PluginName: repository.PLUGIN_LEVELDB,
+		DataPath:   conf.BlockDataPath,
+	})
+	if nil != err {
+		return nil, nil, fmt.Errorf("open block store failed: %v", err)
+	}
+	current := store.GetCurrentBlock()
+	if nil == current {
+		return nil, nil, fmt.Errorf("there is no block in repository")
+	}
+	block := current
+	if LatestHeight != height {
+		if height > current.Header.Height {
+			return nil, nil, fmt.Errorf("height %d is higher than current height %d", height, current.Header.Height)
+		}
+		if block, err = store.GetBlockByHeight(height); nil != err {
+			return nil, nil, fmt.Errorf("get block %d failed: %v", height, err)
+		}
+	}
+	stateDB, err := leveldb.New(conf.StateDataPath, 0, 0, "")
+	if nil != err {
+		return nil, nil, fmt.Errorf("open state database failed: %v", err)
+	}
+	defer stateDB.Close()
+	state, err := statedb.New(block.Header.StateRoot, statedb.NewDatabase(stateDB))
+	if nil != err {
+		return nil, nil, fmt.Errorf("open state of block %d failed: %v", block.Header.Height, err)
+	}
+	genesis, export, err := exportGenesisState(state, block.Header)
+	if nil != err {
+		return nil, nil, err
+	}
+	export.BlockHash = block.HeaderHash
+	log.Info("Exported %d accounts at height %d with state root %x.", export.Accounts, export.Height, export.StateRoot)
+	return genesis, export, nil
+}
+
+// exportGenesisState build genesis file from state, contracts at the addresses of system contracts in genesis
+// file keep their names.
+func exportGenesisState(state *statedb.StateDB, header *types.Header) (genesis *GenesisBlockConfig, export *GenesisExport, err error) {
+	genesis, err = currentGenesisConfig()
+	if nil != err {
+		return nil, nil, err
+	}
+	current, err := GenerateGenesisBlock()
+	if nil != err {
+		return nil, nil, err
+	}
+	names := make(map[string]string)
+	for name, address := range current.SystemContracts() {
+		names[fmt.Sprintf("%x", address)] = name
+	}
+	genesis.Block.Header.Timestamp = header.Timestamp
+	genesis.GenesisAccounts = make([]GenesisAccountConfig, 0)
+	export = &GenesisExport{
+		Height:    header.Height,
+		StateRoot: header.StateRoot,
+	}
+
+	defer func() {
+		// dump panics on state it could not decode
+		if recovered := recover(); nil != recovered {
+			genesis, export, err = nil, nil, fmt.Errorf("dump state failed: %v", recovered)
+		}
+	}()
+	dump := state.RawDump()
+	addresses := make([]string, 0, len(dump.Accounts))
+	for address := range dump.Accounts {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		if !hexAddressRegexp.MatchString(address) {
+			return nil, nil, fmt.Errorf("address of account %s missing in state database", address)
+		}
+		dumped := dump.Accounts[address]
+		balance, ok := new(big.Int).SetString(dumped.Balance, 10)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid balance %s of account %s", dumped.Balance, address)
+		}
+		account := GenesisAccountConfig{
+			Addr:  "0x" + address,
+			Nonce: dumped.Nonce,
+		}
+		if balance.Sign() > 0 {
+			account.Balance = balance
+		}
+		if 0 != len(dumped.Storage) {
+			if account.Storage, err = exportStorage(dumped.Storage); nil != err {
+				return nil, nil, fmt.Errorf("export storage of %s failed: %v", address, err)
+			}
+		}
+		if justitiac.BlankString == dumped.Code {
+			if 0 != len(account.Storage) {
+				return nil, nil, fmt.Errorf("account %s has storage without code", address)
+			}
+			// empty account is kept in state trie, as state is committed without deleting empty objects
+			if nil == account.Balance && 0 == account.Nonce {
+				account.KeepEmpty = true
+				export.Empty++
+			}
+		} else {
+			account.RuntimeCode = "0x" + dumped.Code
+			account.Contract = names[address]
+			export.Contracts++
+		}
+		genesis.GenesisAccounts = append(genesis.GenesisAccounts, account)
+	}
+	export.Accounts = len(genesis.GenesisAccounts)
+	return genesis, export, nil
+}
+
+// exportStorage decode rlp encoded storage values in state trie.
+func exportStorage(storage map[string]string) (map[string]string, error) {
+	exported := make(map[string]string, len(storage))
+	for slot, encoded := range storage {
+		if 2*types.HashLength != len(slot) {
+			return nil, fmt.Errorf("slot of storage value %s missing in state database", encoded)
+		}
+		_, value, _, err := rlp.Split(tools.Hex2Bytes(encoded))
+		if nil != err {
+			return nil, fmt.Errorf("invalid value of slot %s: %v", slot, err)
+		}
+		exported["0x"+slot] = fmt.Sprintf("0x%x", value)
+	}
+	return exported, nil
+}
+
+// currentGenesisConfig return the content of genesis file, or the config of default genesis block without file.
+func currentGenesisConfig() (*GenesisBlockConfig, error) {
+	genesis := &GenesisBlockConfig{}
+	if genesisPath := genesisFilePath(); InvalidPath != genesisPath {
+		var err error
+		if genesis, err = readGenesisConfig(genesisPath); nil != err {
+			return nil, err
+		}
+	}
+	if nil == genesis.Block {
+		genesis.Block = &GenesisBlockSection{}
+	}
+	if nil == genesis.Block.Header {
+		genesis.Block.Header = &GenesisHeaderConfig{}
+	}
+	return genesis, nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/repository"
+	repositoryConfig "github.com/DSiSc/repository/config"
+	"github.com/DSiSc/statedb-NG"
+	"github.com/DSiSc/statedb-NG/ethdb/memorydb"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportGenesisState(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	SetHome(dir)
+	defer SetHome("")
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, GenesisFileName), []byte(`{
+		"Block": {"Header": {"chainId": 9}},
+		"GenesisAccounts": [
+			{"addr": "0x00000000000000000000000000000000000000aa", "runtimeCode": "0x`+loadSlotCode+`", "contract": "MetaData"}
+		],
+		"participates": [{"addr": "0x333c3310824b7c685133f2bedb2ca4b8b4df633d", "id": 0, "url": "127.0.0.1:8080"}]
+	}`), 0644))
+
+	user := tools.HexToAddress("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	metaData := tools.HexToAddress("0x00000000000000000000000000000000000000aa")
+	contract := tools.HexToAddress("0x00000000000000000000000000000000000000bb")
+	database := statedb.NewDatabase(memorydb.New())
+	state, err := statedb.New(types.Hash{}, database)
+	assert.Nil(err)
+	state.CreateAccount(user)
+	state.SetBalance(user, big.NewInt(1000))
+	state.SetNonce(user, 3)
+	for _, address := range []types.Address{metaData, contract} {
+		state.CreateAccount(address)
+		state.SetCode(address, tools.Hex2Bytes(loadSlotCode))
+		state.SetNonce(address, 1)
+	}
+	state.SetBalance(contract, big.NewInt(5))
+	state.SetHashTypeState(contract, types.Hash{31: 1}, types.Hash{31: 7})
+	state.SetState(contract, types.Hash{31: 2}, tools.Hex2Bytes(strings.Repeat("01", 40)))
+	root, err := state.Commit(false)
+	assert.Nil(err)
+	state, err = statedb.New(root, database)
+	assert.Nil(err)
+
+	genesis, export, err := exportGenesisState(state, &types.Header{Height: 5, Timestamp: 1600000000, StateRoot: root})
+	assert.Nil(err)
+	assert.Equal(&GenesisExport{Height: 5, StateRoot: root, Accounts: 3, Contracts: 2}, export)
+	assert.Equal(uint64(9), genesis.Block.Header.ChainID)
+	assert.Equal(uint64(1600000000), genesis.Block.Header.Timestamp)
+	assert.Equal(1, len(genesis.Participates))
+	assert.Equal("MetaData", genesis.GenesisAccounts[0].Contract)
+	assert.Equal("0x07", genesis.GenesisAccounts[1].Storage["0x"+strings.Repeat("0", 63)+"1"])
+	assertGenesisReproduces(assert, dir, genesis, root)
+
+	// empty account is kept in state, so it is exported too
+	state.CreateAccount(tools.HexToAddress("0x00000000000000000000000000000000000000cc"))
+	root, err = state.Commit(false)
+	assert.Nil(err)
+	state, err = statedb.New(root, database)
+	assert.Nil(err)
+	genesis, export, err = exportGenesisState(state, &types.Header{StateRoot: root})
+	assert.Nil(err)
+	assert.Equal(&GenesisExport{StateRoot: root, Accounts: 4, Contracts: 2, Empty: 1}, export)
+	assert.Equal(GenesisAccountConfig{Addr: "0x00000000000000000000000000000000000000cc", KeepEmpty: true}, genesis.GenesisAccounts[2])
+	assertGenesisReproduces(assert, dir, genesis, root)
+}
+
+// assertGenesisReproduces assert importing genesis into an empty chain reproduces the state root.
+func assertGenesisReproduces(assert *assert.Assertions, dir string, genesis *GenesisBlockConfig, root types.Hash) {
+	content, err := json.Marshal(genesis)
+	assert.Nil(err)
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, GenesisFileName), content, 0644))
+	assert.Nil(repository.InitRepository(repositoryConfig.RepositoryConfig{PluginName: repository.PLUGIN_MEMDB}, events.NewEvent()))
+	assert.Nil(ImportGenesisBlock())
+	chain, err := repository.NewLatestStateRepository()
+	assert.Nil(err)
+	assert.Equal(root, chain.GetCurrentBlock().Header.StateRoot)
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/DSiSc/craft/types"
+	justitiac "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/tools"
+	"time"
+)
+
+// MaxGenesisExtraDataSize is the max size of extra data kept in genesis header.
+const MaxGenesisExtraDataSize = 256
+
+// timestamp of genesis block whose genesis file does not specify it
+var defaultGenesisTimestamp = uint64(time.Date(2018, time.August, 28, 0, 0, 0, 0, time.UTC).Unix())
+
+// GenesisBlockSection is the Block section of genesis file, where only header could be configured.
+type GenesisBlockSection struct {
+	Header *GenesisHeaderConfig `json:"Header"`
+}
+
+// GenesisHeaderConfig is the configurable fields of genesis header. Other header fields are derived from the
+// content of genesis block, genesis file with any of them set is rejected.
+type GenesisHeaderConfig struct {
+	ChainID uint64 `json:"chainId"`
+	// unix time of genesis block in seconds, default to 2018-08-28
+	Timestamp uint64 `json:"timestamp,omitempty"`
+	// hex encoded address of block coinbase
+	CoinBase string `json:"coinbase,omitempty"`
+}
+
+// UnmarshalJSON decode genesis header, which may be encoded from a whole block header with derived fields left
+// zero, as genesis files written by earlier versions are.
+func (header *GenesisHeaderConfig) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); nil != err {
+		return err
+	}
+	for key, value := range fields {
+		var err error
+		switch key {
+		case "chainId":
+			err = json.Unmarshal(value, &header.ChainID)
+		case "timestamp":
+			err = json.Unmarshal(value, &header.Timestamp)
+		case "coinbase":
+			if !isZeroJSON(value) {
+				err = json.Unmarshal(value, &header.CoinBase)
+			}
+		default:
+			if !isZeroJSON(value) {
+				return fmt.Errorf("header field %s is derived from genesis block, which could not be set", key)
+			}
+		}
+		if nil != err {
+			return fmt.Errorf("invalid header field %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// isZeroJSON report whether value is null, false, zero, blank, or an array of them.
+func isZeroJSON(value json.RawMessage) bool {
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); nil != err {
+		return false
+	}
+	return isZero(decoded)
+}
+
+func isZero(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case json.Number:
+		number, err := v.Float64()
+		return nil == err && 0 == number
+	case string:
+		return justitiac.BlankString == v
+	case []interface{}:
+		for _, element := range v {
+			if !isZero(element) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// newGenesisHeader build the header of genesis block from genesis file.
+func newGenesisHeader(genesis *GenesisBlockConfig) (*types.Header, error) {
+	header := &types.Header{
+		Timestamp: defaultGenesisTimestamp,
+	}
+	if nil != genesis.Block && nil != genesis.Block.Header {
+		config := genesis.Block.Header
+		header.ChainID = config.ChainID
+		if 0 != config.Timestamp {
+			header.Timestamp = config.Timestamp
+		}
+		if justitiac.BlankString != config.CoinBase {
+			if !hexAddressRegexp.MatchString(config.CoinBase) {
+				return nil, fmt.Errorf("invalid coinbase %s", config.CoinBase)
+			}
+			header.CoinBase = tools.HexToAddress(config.CoinBase)
+		}
+	}
+	if len(genesis.ExtraData) > MaxGenesisExtraDataSize {
+		return nil, fmt.Errorf("extra data of %d bytes exceeds the limit of %d bytes", len(genesis.ExtraData), MaxGenesisExtraDataSize)
+	}
+	if 0 != len(genesis.ExtraData) {
+		header.Extra = genesis.ExtraData
+	}
+	return header, nil
+}
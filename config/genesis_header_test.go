@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"github.com/DSiSc/craft/types"
+	justitiac "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestGenesisHeaderConfig(t *testing.T) {
+	assert := assert.New(t)
+	var genesis GenesisBlockConfig
+	assert.Nil(json.Unmarshal([]byte(`{
+		"Block": {"Header": {"chainId": 3, "timestamp": 1600000000, "coinbase": "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"}},
+		"extra_data": "dGVzdG5ldA=="
+	}`), &genesis))
+	block, err := NewGenesisBlock(&genesis)
+	assert.Nil(err)
+	assert.Equal(uint64(3), block.Block.Header.ChainID)
+	assert.Equal(uint64(1600000000), block.Block.Header.Timestamp)
+	assert.Equal(tools.HexToAddress("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"), block.Block.Header.CoinBase)
+	assert.Equal([]byte("testnet"), block.Block.Header.Extra)
+
+	// distinct timestamp, coinbase or extra data give distinct genesis hash on the same chain
+	defaults, err := NewGenesisBlock(&GenesisBlockConfig{Block: &GenesisBlockSection{Header: &GenesisHeaderConfig{ChainID: 3}}})
+	assert.Nil(err)
+	assert.Equal(defaultGenesisTimestamp, defaults.Block.Header.Timestamp)
+	assert.NotEqual(justitiac.HeaderHash(defaults.Block), justitiac.HeaderHash(block.Block))
+	genesis.ExtraData = nil
+	other, err := NewGenesisBlock(&genesis)
+	assert.Nil(err)
+	assert.NotEqual(justitiac.HeaderHash(other.Block), justitiac.HeaderHash(block.Block))
+
+	// header encoded from a whole block header is accepted while its derived fields are zero
+	encoded, err := json.Marshal(struct{ Block *types.Block }{&types.Block{Header: &types.Header{ChainID: 5}}})
+	assert.Nil(err)
+	var encodedGenesis GenesisBlockConfig
+	assert.Nil(json.Unmarshal(encoded, &encodedGenesis))
+	assert.Equal(uint64(5), encodedGenesis.Block.Header.ChainID)
+}
+
+func TestGenesisHeaderConfig_Invalid(t *testing.T) {
+	assert := assert.New(t)
+	for _, content := range []string{
+		`{"Block": {"Header": {"chainId": 1, "height": 1}}}`,
+		`{"Block": {"Header": {"stateRoot": [1]}}}`,
+		`{"Block": {"Header": {"timestamp": "now"}}}`,
+	} {
+		var genesis GenesisBlockConfig
+		assert.NotNil(json.Unmarshal([]byte(content), &genesis), content)
+	}
+	_, err := NewGenesisBlock(&GenesisBlockConfig{Block: &GenesisBlockSection{Header: &GenesisHeaderConfig{CoinBase: "0x01"}}})
+	assert.NotNil(err)
+	_, err = NewGenesisBlock(&GenesisBlockConfig{ExtraData: []byte(strings.Repeat("x", MaxGenesisExtraDataSize+1))})
+	assert.NotNil(err)
+}
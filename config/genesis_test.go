@@ -3,11 +3,17 @@ package config
 import (
 	"errors"
 	"github.com/DSiSc/craft/types"
+	justitiac "github.com/DSiSc/justitia/common"
 	"github.com/DSiSc/justitia/compiler"
 	"github.com/DSiSc/justitia/tools"
+	"github.com/DSiSc/justitia/tools/events"
 	"github.com/DSiSc/monkey"
 	"github.com/DSiSc/repository"
+	repositoryConfig "github.com/DSiSc/repository/config"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -59,7 +65,19 @@ func TestImportGenesisBlockExistBlockInDB(t *testing.T) {
 			},
 		}
 	})
-	ImportGenesisBlock()
+	genesis, err := buildDefaultGenesis()
+	assert.Nil(err)
+	genesisHash := justitiac.HeaderHash(genesis.Block)
+	var checked types.Hash
+	monkey.Patch(checkGenesis, func(_ *repository.Repository, hash types.Hash, chainId uint64) error {
+		checked = hash
+		return nil
+	})
+	monkey.Patch(checkGenesisDigest, func(*repository.Repository, types.Hash) error {
+		return nil
+	})
+	assert.Nil(ImportGenesisBlock())
+	assert.Equal(genesisHash, checked)
 }
 
 // test import genesis block: have no block in local database
@@ -67,7 +85,7 @@ func TestImportGenesisBlockNoBlockInDB(t *testing.T) {
 	assert := assert.New(t)
 	defer func() {
 		r := recover()
-		assert.NotNil(r)
+		assert.Nil(r)
 	}()
 	defer monkey.UnpatchAll()
 	monkey.Patch(tools.PathExists, func(string) bool {
@@ -83,7 +101,56 @@ func TestImportGenesisBlockNoBlockInDB(t *testing.T) {
 	monkey.Patch(GenerateGenesisBlock, func() (*GenesisBlock, error) {
 		return nil, errors.New("failed to build genesis block")
 	})
-	ImportGenesisBlock()
+	assert.NotNil(ImportGenesisBlock())
+}
+
+func TestImportGenesisBlockCheckChain(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	SetHome(dir)
+	defer SetHome("")
+	writeGenesis := func(genesis string) {
+		assert.Nil(ioutil.WriteFile(filepath.Join(dir, GenesisFileName), []byte(genesis), 0644))
+	}
+	writeGenesis(`{"Block": {"Header": {"chainId": 1}}, "GenesisAccounts": [{"addr": "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", "balance": 10}]}`)
+	assert.Nil(repository.InitRepository(repositoryConfig.RepositoryConfig{PluginName: repository.PLUGIN_MEMDB}, events.NewEvent()))
+	assert.Nil(ImportGenesisBlock())
+	chain, err := repository.NewLatestStateRepository()
+	assert.Nil(err)
+	recorded, err := chain.Get(genesisHashKey)
+	assert.Nil(err)
+	assert.Equal(chain.GetCurrentBlock().HeaderHash[:], recorded)
+	assert.Nil(ImportGenesisBlock())
+
+	writeGenesis(`{"Block": {"Header": {"chainId": 2}}}`)
+	err = ImportGenesisBlock()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "chain id 2")
+	writeGenesis(`{"Block": {"Header": {"chainId": 1, "timestamp": 1600000000}}}`)
+	err = ImportGenesisBlock()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "genesis hash")
+	// changes of accounts are not covered by genesis hash
+	writeGenesis(`{"Block": {"Header": {"chainId": 1}}, "GenesisAccounts": [{"addr": "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", "balance": 20}]}`)
+	err = ImportGenesisBlock()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "genesis digest")
+	writeGenesis(`{"Block": {"Header": {"chainId": 1}}, "GenesisAccounts": [{"addr": "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", "balance": 10}], "participates": [{"addr": "0x333c3310824b7c685133f2bedb2ca4b8b4df633d", "id": 0, "url": "127.0.0.1:8080"}]}`)
+	assert.NotNil(ImportGenesisBlock())
+
+	// chain imported before genesis was recorded takes it from block 0
+	assert.Nil(chain.Delete(genesisHashKey))
+	assert.Nil(chain.Delete(genesisChainIdKey))
+	assert.Nil(chain.Delete(genesisDigestKey))
+	writeGenesis(`{"Block": {"Header": {"chainId": 1}}}`)
+	assert.Nil(ImportGenesisBlock())
+	recorded, err = chain.Get(genesisHashKey)
+	assert.Nil(err)
+	assert.Equal(chain.GetCurrentBlock().HeaderHash[:], recorded)
+	_, err = chain.Get(genesisDigestKey)
+	assert.Nil(err)
 }
 
 func TestGetChainIdFromConfigFailed(t *testing.T) {
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/tools"
+	"os"
+	"path/filepath"
+)
+
+// directories under home
+const (
+	ConfigDirName = "config"
+	DataDirName   = "data"
+	P2PDirName    = "p2p"
+	LogDirName    = "logs"
+)
+
+// default paths under home, which are used when justitia.yaml leaves them empty.
+var (
+	DefaultStatePath = filepath.Join(DataDirName, "state")
+	DefaultBlockPath = filepath.Join(DataDirName, "blocks")
+	DefaultLogPath   = filepath.Join(LogDirName, "justitia.log")
+	DefaultAddrBooks = map[string]string{
+		BlockSyncerP2P: filepath.Join(P2PDirName, "addrbook-syncer.json"),
+		BlockP2P:       filepath.Join(P2PDirName, "addrbook-block.json"),
+		TxP2P:          filepath.Join(P2PDirName, "addrbook-tx.json"),
+	}
+)
+
+// home directory and config file specified by command line, which take precedence over HomeEnv.
+var (
+	homeDir    string
+	configFile string
+)
+
+// SetHome make dir the root of config, data, p2p and log directories.
+func SetHome(dir string) {
+	homeDir = dir
+}
+
+// SetConfigFile read node config from file, instead of justitia.yaml in config directory.
+func SetConfigFile(file string) {
+	configFile = file
+}
+
+// DefaultHome return the home directory used when neither SetHome nor HomeEnv specify one.
+func DefaultHome() string {
+	homePath, _ := tools.Home()
+	return filepath.Join(homePath, ".justitia")
+}
+
+// Home return the directory specified by SetHome or HomeEnv, or the default one.
+func Home() string {
+	if common.BlankString != homeDir {
+		return homeDir
+	}
+	if home := os.Getenv(HomeEnv); common.BlankString != home {
+		return home
+	}
+	return DefaultHome()
+}
+
+// ConfigDir return the directory holding justitia.yaml and genesis.json.
+func ConfigDir() string {
+	return filepath.Join(Home(), ConfigDirName)
+}
+
+// ResolvePath return path relative to home in absolute path, or fallback if path is empty.
+func ResolvePath(path string, fallback string) string {
+	if common.BlankString == path {
+		path = fallback
+	}
+	if common.BlankString == path || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(Home(), path)
+}
+
+// configDirs return the directories to look for config and genesis file in order, files placed
+// directly in home are also accepted.
+func configDirs() []string {
+	return []string{ConfigDir(), Home()}
+}
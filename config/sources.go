@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/common"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// sources of node config values
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// RedactedValue is shown instead of the value of a secret key.
+const RedactedValue = "<redacted>"
+
+// secretKeys are keys whose values are redacted when node config is described.
+var secretKeys = map[string]bool{}
+
+// ConfigValue is a resolved value of node config with the source it comes from.
+type ConfigValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// envKey return the environment variable overriding key, the same way as viper looks it up.
+func envKey(key string) string {
+	return strings.Replace(strings.ToUpper(ConfigPrefix+"_"+key), ".", "_", -1)
+}
+
+// valueSources tell whether each key of node config is given by environment variable or config file,
+// keys given by neither take their default values.
+func valueSources(config *viper.Viper) map[string]string {
+	sources := make(map[string]string)
+	for _, configValue := range nodeConfigValues {
+		if value, ok := os.LookupEnv(envKey(configValue.key)); ok && common.BlankString != value {
+			sources[configValue.key] = SourceEnv
+		} else if config.InConfig(configValue.key) {
+			sources[configValue.key] = SourceFile
+		} else {
+			sources[configValue.key] = SourceDefault
+		}
+	}
+	return sources
+}
+
+// SetValueSource record that keys of conf take their values from source. Config built without
+// loading keeps no sources, and is left untouched.
+func SetValueSource(conf NodeConfig, source string, keys ...string) {
+	if nil == conf.Sources {
+		return
+	}
+	for _, key := range keys {
+		conf.Sources[key] = source
+	}
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return common.BlankString
+	case types.Address:
+		return fmt.Sprintf("0x%x", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// DescribeNodeConfig list the values of conf in the order of justitia.yaml with their sources,
+// values of secret keys are redacted unless they are empty.
+func DescribeNodeConfig(conf NodeConfig) []ConfigValue {
+	values := make([]ConfigValue, 0, len(nodeConfigValues))
+	for _, configValue := range nodeConfigValues {
+		value := formatValue(configValue.value(&conf))
+		if secretKeys[configValue.key] && common.BlankString != value {
+			value = RedactedValue
+		}
+		source := conf.Sources[configValue.key]
+		if common.BlankString == source {
+			source = SourceDefault
+		}
+		values = append(values, ConfigValue{
+			Key:    configValue.key,
+			Value:  value,
+			Source: source,
+		})
+	}
+	return values
+}
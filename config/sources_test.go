@@ -0,0 +1,47 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestEnvKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("JUSTITIA_GENERAL_TXPOOL_GLOBALSLOTS", envKey(TxpoolSlots))
+	assert.Equal("JUSTITIA_GENERAL_P2P_BLOCK_LISTENADDRESS", envKey(BlockP2P+"."+P2PListenAddr))
+}
+
+func TestDescribeNodeConfig(t *testing.T) {
+	assert := assert.New(t)
+	os.Setenv(envKey(ApiGatewayAddr), "tcp://127.0.0.1:8545")
+	defer os.Unsetenv(envKey(ApiGatewayAddr))
+	conf, err := LoadNodeConfig()
+	assert.Nil(err)
+	SetValueSource(conf, SourceFlag, LogFileLevel)
+
+	values := make(map[string]ConfigValue)
+	for _, value := range DescribeNodeConfig(conf) {
+		values[value.Key] = value
+	}
+	assert.Equal(len(nodeConfigValues), len(values))
+	assert.Equal(ConfigValue{ApiGatewayAddr, "tcp://127.0.0.1:8545", SourceEnv}, values[ApiGatewayAddr])
+	assert.Equal(ConfigValue{NodeAddress, "0x333c3310824b7c685133f2bedb2ca4b8b4df633d", SourceFile}, values[NodeAddress])
+	assert.Equal(SourceFlag, values[LogFileLevel].Source)
+
+	// values of secret keys are redacted
+	secretKeys[NodeAddress] = true
+	defer delete(secretKeys, NodeAddress)
+	for _, value := range DescribeNodeConfig(conf) {
+		if NodeAddress == value.Key {
+			assert.Equal(RedactedValue, value.Value)
+		}
+	}
+
+	// config built without loading keeps no sources
+	var built NodeConfig
+	SetValueSource(built, SourceFlag, LogFileLevel)
+	for _, value := range DescribeNodeConfig(built) {
+		assert.Equal(SourceDefault, value.Source)
+	}
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
+	participatesCommon "github.com/DSiSc/galaxy/participates/common"
+	roleCommon "github.com/DSiSc/galaxy/role/common"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/propagator"
+	"github.com/DSiSc/repository"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hash algorithms supported by crypto suite
+var hashAlgorithms = []string{"SHA256", "Keccak256", "Keccak512"}
+
+// FieldError describe an invalid value of node config by its yaml key.
+type FieldError struct {
+	Key     string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// ValidationError collect all problems found in node config.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	problems := make([]string, 0, len(e))
+	for _, field := range e {
+		problems = append(problems, field.Error())
+	}
+	return fmt.Sprintf("invalid node config: %s", strings.Join(problems, "; "))
+}
+
+// validator record problems of node config in the order they are found.
+type validator struct {
+	errs ValidationError
+}
+
+func (v *validator) fail(key string, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) oneOf(key string, value string, allowed ...string) {
+	for _, candidate := range allowed {
+		if candidate == value {
+			return
+		}
+	}
+	v.fail(key, "unknown value %q, which should be one of [%s]", value, strings.Join(allowed, ", "))
+}
+
+// Validate check node config up front, and return a ValidationError listing every invalid field.
+// Policies of consensus are only checked on consensus node, which is the only one using them.
+func Validate(conf NodeConfig) error {
+	v := &validator{}
+	if conf.NodeType <= common.UnknownNode || conf.NodeType >= common.MaxNodeType {
+		v.fail(NodeType, "unknown node type %d, which should be one of [1, 2, 3]", conf.NodeType)
+	}
+	v.oneOf(HashAlgorithm, conf.AlgorithmConf.HashAlgorithm, hashAlgorithms...)
+	if common.ConsensusNode == conf.NodeType {
+		validateConsensus(v, conf)
+	}
+	if common.LightNode == conf.NodeType {
+		if 0 == len(conf.LightConf.FullPeers) {
+			v.fail(LightFullPeers, "light node needs at least one full peer")
+		}
+		for _, validator := range conf.LightConf.Validators {
+			if !hexAddressRegexp.MatchString(validator) {
+				v.fail(LightValidators, "invalid address %s, which should be 40 hex characters", validator)
+			}
+		}
+	} else {
+		if 0 == conf.TxPoolConf.GlobalSlots {
+			v.fail(TxpoolSlots, "should be positive")
+		}
+		if 0 == conf.TxPoolConf.MaxTrsPerBlock {
+			v.fail(MaxTxBlock, "should be positive")
+		}
+		v.oneOf(PropagatorDropPolicy, conf.PropagatorConf.DropPolicy, propagator.DropOldest, propagator.DropLowestScore)
+	}
+	validateRepository(v, conf)
+	validateListeners(v, conf)
+	validateLog(v, conf)
+	if 0 == len(v.errs) {
+		return nil
+	}
+	return v.errs
+}
+
+func validateConsensus(v *validator, conf NodeConfig) {
+	v.oneOf(ParticipatesPolicy, conf.ParticipatesConf.PolicyName,
+		participatesCommon.SoloPolicy, participatesCommon.DposPolicy, GenesisParticipatesPolicy)
+	v.oneOf(RolePolicy, conf.RoleConf.PolicyName, roleCommon.SoloPolicy, roleCommon.DposPolicy)
+	v.oneOf(ConsensusPolicy, conf.ConsensusConf.PolicyName, consensusCommon.SoloPolicy,
+		consensusCommon.BftPolicy, consensusCommon.FbftPolicy, consensusCommon.DbftPolicy)
+	if (types.Address{}) == conf.Account.Address {
+		v.fail(NodeAddress, "consensus node needs an address")
+	}
+	if conf.BlockInterval <= 0 {
+		v.fail(BlockProducedTimeInterval, "should be positive")
+	}
+	if consensusCommon.SoloPolicy != conf.ConsensusConf.PolicyName {
+		timeouts := []struct {
+			key   string
+			value int64
+		}{
+			{ConsensusTimeoutToCollectResponse, conf.ConsensusConf.Timeout.TimeoutToCollectResponseMsg},
+			{ConsensusTimeoutWaitCommit, conf.ConsensusConf.Timeout.TimeoutToWaitCommitMsg},
+			{ConsensusTimeoutViewChange, conf.ConsensusConf.Timeout.TimeoutToChangeView},
+		}
+		for _, timeout := range timeouts {
+			if timeout.value <= 0 {
+				v.fail(timeout.key, "should be positive")
+			}
+		}
+	}
+}
+
+func validateRepository(v *validator, conf NodeConfig) {
+	repositoryConf := conf.RepositoryConf
+	v.oneOf(RepositoryPlugin, repositoryConf.PluginName, repository.PLUGIN_MEMDB, repository.PLUGIN_LEVELDB)
+	if repository.PLUGIN_LEVELDB != repositoryConf.PluginName {
+		return
+	}
+	if common.BlankString == repositoryConf.StateDataPath {
+		v.fail(RepositoryStatePath, "leveldb plugin needs a state path")
+	}
+	if common.BlankString == repositoryConf.BlockDataPath {
+		v.fail(RepositoryDataPath, "leveldb plugin needs a data path")
+	}
+	if common.BlankString != repositoryConf.StateDataPath && repositoryConf.StateDataPath == repositoryConf.BlockDataPath {
+		v.fail(RepositoryDataPath, "should differ from %s", RepositoryStatePath)
+	}
+}
+
+// validateListeners check every address node listens on, and that no port is taken twice.
+func validateListeners(v *validator, conf NodeConfig) {
+	listeners := make(map[string]string)
+	if common.BlankString != conf.ApiGatewayAddr {
+		listeners[ApiGatewayAddr] = conf.ApiGatewayAddr
+	}
+	if common.BlankString != conf.AdminGatewayAddr {
+		listeners[AdminGatewayAddr] = conf.AdminGatewayAddr
+	}
+	p2ps := []string{BlockSyncerP2P, BlockP2P, TxP2P}
+	if common.LightNode == conf.NodeType {
+		p2ps = []string{BlockSyncerP2P}
+	}
+	for _, p2pType := range p2ps {
+		key := p2pType + "." + P2PListenAddr
+		if p2pConf, ok := conf.P2PConf[p2pType]; !ok || common.BlankString == p2pConf.ListenAddress {
+			v.fail(key, "listen address not specified")
+		} else {
+			listeners[key] = p2pConf.ListenAddress
+		}
+	}
+	if conf.PrometheusConf.PrometheusEnabled {
+		listeners[PrometheusPort] = conf.PrometheusConf.PrometheusPort
+	}
+	if conf.ExpvarConf.ExpvarEnabled {
+		listeners[ExpvarPort] = conf.ExpvarConf.ExpvarPort
+	}
+	if conf.PprofConf.PprofEnabled {
+		listeners[PprofPort] = conf.PprofConf.PprofPort
+	}
+
+	keys := make([]string, 0, len(listeners))
+	for key := range listeners {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	owners := make(map[int]string)
+	for _, key := range keys {
+		port, err := listenPort(listeners[key])
+		if nil != err {
+			v.fail(key, "%v", err)
+			continue
+		}
+		if owner, ok := owners[port]; ok {
+			v.fail(key, "port %d is also used by %s", port, owner)
+			continue
+		}
+		owners[port] = key
+	}
+}
+
+// listenPort return the port of address in form of port, host:port or tcp://host:port.
+func listenPort(address string) (int, error) {
+	if index := strings.Index(address, "://"); index >= 0 {
+		address = address[index+len("://"):]
+	}
+	portString := address
+	if strings.Contains(address, ":") {
+		var err error
+		if _, portString, err = net.SplitHostPort(address); nil != err {
+			return 0, fmt.Errorf("invalid address %q", address)
+		}
+	}
+	port, err := strconv.Atoi(portString)
+	if nil != err || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", portString)
+	}
+	return port, nil
+}
+
+func validateLog(v *validator, conf NodeConfig) {
+	appenders := []struct {
+		name   string
+		level  string
+		format string
+	}{
+		{ConsoleLogAppender, LogConsoleLevel, LogConsoleFormat},
+		{FileLogAppender, LogFileLevel, LogFileFormat},
+	}
+	for _, appender := range appenders {
+		setting, ok := conf.Logger.Appenders[appender.name]
+		if !ok || !setting.Enabled {
+			continue
+		}
+		if setting.LogLevel > log.Disabled {
+			v.fail(appender.level, "unknown level %d, which should be in [0, %d]", setting.LogLevel, log.Disabled)
+		}
+		if log.TextFmt != setting.Format && log.JsonFmt != setting.Format {
+			v.fail(appender.format, "unknown format %q, which should be one of [text, json]", strings.ToLower(setting.Format))
+		}
+	}
+	if file, ok := conf.Logger.Appenders[FileLogAppender]; ok && file.Enabled && common.BlankString == file.LogPath {
+		v.fail(LogFilePath, "file log needs a path")
+	}
+}
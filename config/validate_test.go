@@ -0,0 +1,77 @@
+package config
+
+import (
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/repository"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func validationKeys(err error) []string {
+	var keys []string
+	for _, field := range err.(ValidationError) {
+		keys = append(keys, field.Key)
+	}
+	return keys
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	conf, _ := NewNodeConfig()
+	assert.Nil(Validate(conf))
+
+	conf.NodeType = common.NodeType(7)
+	conf.AlgorithmConf.HashAlgorithm = "SM3"
+	conf.RepositoryConf.PluginName = repository.PLUGIN_LEVELDB
+	conf.RepositoryConf.StateDataPath = common.BlankString
+	conf.ExpvarConf.ExpvarPort = conf.PrometheusConf.PrometheusPort
+	conf.P2PConf[TxP2P].ListenAddress = "tcp://0.0.0.0:port"
+	conf.PropagatorConf.DropPolicy = "newest"
+	err := Validate(conf)
+	assert.NotNil(err)
+	assert.Equal([]string{
+		NodeType,
+		HashAlgorithm,
+		PropagatorDropPolicy,
+		RepositoryStatePath,
+		TxP2P + "." + P2PListenAddr,
+		PrometheusPort,
+	}, validationKeys(err))
+	assert.Contains(err.Error(), "port 47780 is also used by "+ExpvarPort)
+}
+
+func TestValidate_ConsensusNode(t *testing.T) {
+	assert := assert.New(t)
+	conf, _ := NewNodeConfig()
+	conf.ParticipatesConf.PolicyName = "pos"
+	conf.ConsensusConf.PolicyName = "fbft"
+	conf.ConsensusConf.Timeout.TimeoutToChangeView = 0
+	conf.BlockInterval = 0
+	assert.Equal([]string{
+		ParticipatesPolicy,
+		BlockProducedTimeInterval,
+		ConsensusTimeoutViewChange,
+	}, validationKeys(Validate(conf)))
+
+	conf, _ = NewNodeConfig()
+	conf.NodeType = common.LightNode
+	conf.LightConf.FullPeers = nil
+	conf.LightConf.Validators = []string{"0x333c3310824b7c685133f2bedb2ca4b8b4df633d", "0x333c"}
+	conf.ConsensusConf.PolicyName = "pow"
+	conf.P2PConf[TxP2P].ListenAddress = common.BlankString
+	assert.Equal([]string{LightFullPeers, LightValidators}, validationKeys(Validate(conf)))
+}
+
+func TestListenPort(t *testing.T) {
+	assert := assert.New(t)
+	port, err := listenPort("tcp://0.0.0.0:47768")
+	assert.Nil(err)
+	assert.Equal(47768, port)
+	port, err = listenPort("6060")
+	assert.Nil(err)
+	assert.Equal(6060, port)
+	_, err = listenPort("127.0.0.1:70000")
+	assert.NotNil(err)
+	_, err = listenPort(common.BlankString)
+	assert.NotNil(err)
+}
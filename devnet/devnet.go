@@ -0,0 +1,234 @@
+package devnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
+	roleCommon "github.com/DSiSc/galaxy/role/common"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// host all devnet nodes listen on
+	LoopbackHost = "127.0.0.1"
+	// config file of node
+	ConfigFileName = config.ConfigPrefix + ".yaml"
+	// balance of each node account in genesis block
+	NodeBalance = 1000000000
+)
+
+// ValidatorAddresses are the only signers accepted by the signature verification of validator, so devnet
+// nodes take them as accounts in order, which limits a devnet to as many nodes.
+var ValidatorAddresses = []string{
+	"333c3310824b7c685133f2bedb2ca4b8b4df633d",
+	"343c3310824b7c685133f2bedb2ca4b8b4df633d",
+	"353c3310824b7c685133f2bedb2ca4b8b4df633d",
+	"363c3310824b7c685133f2bedb2ca4b8b4df633d",
+}
+
+// ports taken by a node, numbered from node's first port.
+const (
+	apiGatewayPort = iota
+	adminGatewayPort
+	blockSyncerPort
+	blockPort
+	txPort
+	consensusPort
+	prometheusPort
+	expvarPort
+	pprofPort
+	// ports reserved for each node
+	PortsPerNode = 10
+)
+
+// Config describe the devnet to generate.
+type Config struct {
+	// number of consensus nodes
+	Nodes int
+	// consensus policy: solo, bft, fbft or dbft
+	Consensus string
+	// directory holding the home directories of nodes
+	Dir string
+	// first port of the first node, each node takes PortsPerNode ports
+	BasePort int
+	// chain id recorded in genesis block
+	ChainId uint64
+	// block produce interval in millisecond
+	BlockInterval int64
+}
+
+// Node is a generated devnet node.
+type Node struct {
+	Index   int
+	Home    string
+	Address types.Address
+	// first port taken by node
+	BasePort int
+}
+
+// ApiGateway return the address of node's api gateway.
+func (node *Node) ApiGateway() string {
+	return node.url(apiGatewayPort)
+}
+
+// AdminGateway return the address of node's admin gateway.
+func (node *Node) AdminGateway() string {
+	return node.url(adminGatewayPort)
+}
+
+func (node *Node) port(offset int) int {
+	return node.BasePort + offset
+}
+
+func (node *Node) hostPort(offset int) string {
+	return fmt.Sprintf("%s:%d", LoopbackHost, node.port(offset))
+}
+
+func (node *Node) url(offset int) string {
+	return "tcp://" + node.hostPort(offset)
+}
+
+// Generate write a genesis file funding node accounts and listing them as participates, and the config file
+// of each node under conf.Dir. The home directory of node i is conf.Dir/node<i>, data and logs of the node
+// are placed there in the default layout.
+func Generate(conf Config) ([]*Node, error) {
+	if err := checkConfig(conf); nil != err {
+		return nil, err
+	}
+	nodes := make([]*Node, 0, conf.Nodes)
+	for i := 0; i < conf.Nodes; i++ {
+		node := &Node{
+			Index:    i,
+			Home:     filepath.Join(conf.Dir, fmt.Sprintf("node%d", i)),
+			BasePort: conf.BasePort + i*PortsPerNode,
+		}
+		if err := os.MkdirAll(filepath.Join(node.Home, config.ConfigDirName), 0755); nil != err {
+			log.Error("Create home directory of node %d failed with error %v.", i, err)
+			return nil, fmt.Errorf("create home directory of node %d failed: %v", i, err)
+		}
+		node.Address = tools.HexToAddress(ValidatorAddresses[i])
+		nodes = append(nodes, node)
+	}
+	genesis, err := json.MarshalIndent(genesisConfig(conf, nodes), "", "  ")
+	if nil != err {
+		return nil, fmt.Errorf("encode genesis failed: %v", err)
+	}
+	for _, node := range nodes {
+		if err := ioutil.WriteFile(filepath.Join(node.Home, config.ConfigDirName, config.GenesisFileName), genesis, 0644); nil != err {
+			log.Error("Write genesis file of node %d failed with error %v.", node.Index, err)
+			return nil, fmt.Errorf("write genesis file of node %d failed: %v", node.Index, err)
+		}
+		if err := writeNodeConfig(conf, node, nodes); nil != err {
+			log.Error("Write config file of node %d failed with error %v.", node.Index, err)
+			return nil, fmt.Errorf("write config file of node %d failed: %v", node.Index, err)
+		}
+	}
+	return nodes, nil
+}
+
+func checkConfig(conf Config) error {
+	if conf.Nodes < 1 || conf.Nodes > len(ValidatorAddresses) {
+		return fmt.Errorf("devnet runs 1 to %d nodes, got %d", len(ValidatorAddresses), conf.Nodes)
+	}
+	switch conf.Consensus {
+	case consensusCommon.SoloPolicy:
+		if 1 != conf.Nodes {
+			return fmt.Errorf("solo consensus runs a single node, got %d", conf.Nodes)
+		}
+	case consensusCommon.BftPolicy, consensusCommon.FbftPolicy, consensusCommon.DbftPolicy:
+	default:
+		return fmt.Errorf("unsupported consensus policy %s", conf.Consensus)
+	}
+	if conf.BasePort < 1 || conf.BasePort+conf.Nodes*PortsPerNode > 65536 {
+		return fmt.Errorf("ports from %d are out of range for %d nodes", conf.BasePort, conf.Nodes)
+	}
+	if common.BlankString == conf.Dir {
+		return fmt.Errorf("devnet directory not specified")
+	}
+	return nil
+}
+
+// genesisConfig fund node accounts, and list them as participates with their consensus urls.
+func genesisConfig(conf Config, nodes []*Node) *config.GenesisBlockConfig {
+	genesis := &config.GenesisBlockConfig{
+		Block: &config.GenesisBlockSection{
+			Header: &config.GenesisHeaderConfig{
+				ChainID: conf.ChainId,
+			},
+		},
+	}
+	for _, node := range nodes {
+		genesis.GenesisAccounts = append(genesis.GenesisAccounts, config.GenesisAccountConfig{
+			Addr:    fmt.Sprintf("0x%x", node.Address),
+			Balance: big.NewInt(NodeBalance),
+		})
+		genesis.Participates = append(genesis.Participates, config.GenesisParticipate{
+			Addr: fmt.Sprintf("0x%x", node.Address),
+			Id:   uint64(node.Index),
+			Url:  node.hostPort(consensusPort),
+		})
+	}
+	return genesis
+}
+
+// writeNodeConfig write the default justitia.yaml with node's account and ports into its config directory.
+func writeNodeConfig(conf Config, node *Node, nodes []*Node) error {
+	nodeConfig := viper.New()
+	nodeConfig.SetConfigType("yaml")
+	if err := nodeConfig.ReadConfig(bytes.NewReader(config.DefaultConfigFile)); nil != err {
+		return fmt.Errorf("read default config failed: %v", err)
+	}
+	rolePolicy := roleCommon.DposPolicy
+	if consensusCommon.SoloPolicy == conf.Consensus {
+		rolePolicy = roleCommon.SoloPolicy
+	}
+	settings := map[string]interface{}{
+		config.NodeType:                  int(common.ConsensusNode),
+		config.NodeAddress:               fmt.Sprintf("%x", node.Address),
+		config.ApiGatewayAddr:            node.ApiGateway(),
+		config.AdminGatewayAddr:          node.AdminGateway(),
+		config.RepositoryPlugin:          "leveldb",
+		config.ParticipatesPolicy:        config.GenesisParticipatesPolicy,
+		config.RolePolicy:                rolePolicy,
+		config.ConsensusPolicy:           conf.Consensus,
+		config.ConsensusEnableEmptyBlock: true,
+		config.PrometheusPort:            strconv.Itoa(node.port(prometheusPort)),
+		config.ExpvarPort:                strconv.Itoa(node.port(expvarPort)),
+		config.PprofPort:                 strconv.Itoa(node.port(pprofPort)),
+	}
+	if conf.BlockInterval > 0 {
+		settings[config.BlockProducedTimeInterval] = conf.BlockInterval
+	}
+	p2ps := map[string]int{
+		config.BlockSyncerP2P: blockSyncerPort,
+		config.BlockP2P:       blockPort,
+		config.TxP2P:          txPort,
+	}
+	for p2pType, port := range p2ps {
+		var peers []string
+		for _, peer := range nodes {
+			if peer != node {
+				peers = append(peers, peer.url(port))
+			}
+		}
+		settings[p2pType+"."+config.P2PListenAddr] = node.url(port)
+		settings[p2pType+"."+config.P2PPersistendPeers] = strings.Join(peers, ",")
+		settings[p2pType+"."+config.P2PDisableDNSSeed] = true
+	}
+	for key, value := range settings {
+		nodeConfig.Set(key, value)
+	}
+	return nodeConfig.WriteConfigAs(filepath.Join(node.Home, config.ConfigDirName, ConfigFileName))
+}
@@ -0,0 +1,75 @@
+package devnet
+
+import (
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "devnet")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	nodes, err := Generate(Config{Nodes: 4, Consensus: "fbft", Dir: dir, BasePort: 30000})
+	assert.Nil(err)
+	assert.Equal(4, len(nodes))
+	ports := make(map[string]bool)
+	for _, node := range nodes {
+		assert.Equal(tools.HexToAddress(ValidatorAddresses[node.Index]), node.Address)
+		assert.True(tools.PathExists(filepath.Join(node.Home, config.ConfigDirName, config.GenesisFileName)))
+		nodeConfig := viper.New()
+		nodeConfig.SetConfigFile(filepath.Join(node.Home, config.ConfigDirName, ConfigFileName))
+		assert.Nil(nodeConfig.ReadInConfig())
+		assert.Equal(config.GenesisParticipatesPolicy, nodeConfig.GetString(config.ParticipatesPolicy))
+		assert.Equal("dpos", nodeConfig.GetString(config.RolePolicy))
+		assert.Equal("fbft", nodeConfig.GetString(config.ConsensusPolicy))
+		assert.Equal(config.DefaultStatePath, nodeConfig.GetString(config.RepositoryStatePath))
+		assert.Equal(2, strings.Count(nodeConfig.GetString(config.BlockP2P+"."+config.P2PPersistendPeers), ","))
+		for _, key := range []string{
+			config.ApiGatewayAddr,
+			config.AdminGatewayAddr,
+			config.BlockSyncerP2P + "." + config.P2PListenAddr,
+			config.BlockP2P + "." + config.P2PListenAddr,
+			config.TxP2P + "." + config.P2PListenAddr,
+			config.PrometheusPort,
+			config.ExpvarPort,
+			config.PprofPort,
+		} {
+			value := nodeConfig.GetString(key)
+			port := value[strings.LastIndex(value, ":")+1:]
+			assert.False(ports[port], "port %s of %s is taken twice", port, key)
+			ports[port] = true
+		}
+	}
+
+	os.Setenv(config.HomeEnv, nodes[2].Home)
+	defer os.Unsetenv(config.HomeEnv)
+	_, err = config.LoadNodeConfig()
+	assert.Nil(err)
+	participates, err := config.GetParticipatesFromGenesis()
+	assert.Nil(err)
+	assert.Equal(4, len(participates))
+	assert.Equal(nodes[2].Address, participates[2].Address)
+	assert.Equal(uint64(2), participates[2].Extension.Id)
+	assert.Equal("127.0.0.1:30025", participates[2].Extension.Url)
+}
+
+func TestGenerate_InvalidConfig(t *testing.T) {
+	assert := assert.New(t)
+	_, err := Generate(Config{Nodes: 5, Consensus: "fbft", Dir: "devnet", BasePort: 30000})
+	assert.NotNil(err)
+	_, err = Generate(Config{Nodes: 2, Consensus: "solo", Dir: "devnet", BasePort: 30000})
+	assert.NotNil(err)
+	_, err = Generate(Config{Nodes: 4, Consensus: "pow", Dir: "devnet", BasePort: 30000})
+	assert.NotNil(err)
+	_, err = Generate(Config{Nodes: 4, Consensus: "fbft", Dir: "devnet", BasePort: 65530})
+	assert.NotNil(err)
+}
@@ -0,0 +1,90 @@
+package devnet
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/justitia/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// file in log directory of node home which receives the console output of node process
+const ConsoleFileName = "console.log"
+
+// Run start each node as a child process of binary with JUSTITIA_HOME set to its home, and wait until
+// stop is closed or any node exits. All nodes are then interrupted, and killed if they are still
+// running after timeout.
+func Run(binary string, nodes []*Node, stop <-chan struct{}, timeout time.Duration) error {
+	exited := make(chan error, len(nodes))
+	var processes []*exec.Cmd
+	var err error
+	for _, node := range nodes {
+		var process *exec.Cmd
+		if process, err = startNode(binary, node, exited); nil != err {
+			break
+		}
+		processes = append(processes, process)
+	}
+	running := len(processes)
+	if nil == err {
+		select {
+		case <-stop:
+		case err = <-exited:
+			running--
+		}
+	}
+	for _, process := range processes {
+		process.Process.Signal(os.Interrupt)
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for running > 0 {
+		select {
+		case <-exited:
+			running--
+		case <-deadline.C:
+			log.Warn("Devnet nodes do not exit within %v, kill them.", timeout)
+			for _, process := range processes {
+				process.Process.Kill()
+			}
+		}
+	}
+	return err
+}
+
+func startNode(binary string, node *Node, exited chan<- error) (*exec.Cmd, error) {
+	logDir := filepath.Join(node.Home, config.LogDirName)
+	if err := os.MkdirAll(logDir, 0755); nil != err {
+		log.Error("Create log directory of node %d failed with error %v.", node.Index, err)
+		return nil, fmt.Errorf("create log directory of node %d failed: %v", node.Index, err)
+	}
+	console, err := os.OpenFile(filepath.Join(logDir, ConsoleFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if nil != err {
+		log.Error("Open console file of node %d failed with error %v.", node.Index, err)
+		return nil, fmt.Errorf("open console file of node %d failed: %v", node.Index, err)
+	}
+	process := exec.Command(binary)
+	process.Dir = node.Home
+	process.Env = append(os.Environ(), config.HomeEnv+"="+node.Home)
+	process.Stdout = console
+	process.Stderr = console
+	if err := process.Start(); nil != err {
+		console.Close()
+		log.Error("Start node %d failed with error %v.", node.Index, err)
+		return nil, fmt.Errorf("start node %d failed: %v", node.Index, err)
+	}
+	log.Info("Node %d started with pid %d in %s.", node.Index, process.Process.Pid, node.Home)
+	go func() {
+		err := process.Wait()
+		console.Close()
+		if nil == err {
+			err = fmt.Errorf("node %d exited", node.Index)
+		} else {
+			err = fmt.Errorf("node %d exited with %v", node.Index, err)
+		}
+		exited <- err
+	}()
+	return process, nil
+}
@@ -4,28 +4,32 @@ go 1.25.3
 
 require (
 	github.com/DSiSc/apigateway v1.1.0
+	github.com/DSiSc/blockstore v1.1.0
 	github.com/DSiSc/craft v1.1.0
 	github.com/DSiSc/crypto-suite v1.1.0
+	github.com/DSiSc/evm-NG v1.1.0
 	github.com/DSiSc/galaxy v1.1.0
 	github.com/DSiSc/gossipswitch v1.1.0
 	github.com/DSiSc/monkey v1.0.1
 	github.com/DSiSc/p2p v1.1.0
 	github.com/DSiSc/producer v1.1.0
 	github.com/DSiSc/repository v1.1.0
+	github.com/DSiSc/statedb-NG v1.1.0
 	github.com/DSiSc/syncer v1.1.0
 	github.com/DSiSc/txpool v1.1.0
 	github.com/DSiSc/validator v1.1.0
+	github.com/DSiSc/wasm v0.6.0
+	github.com/prometheus/client_golang v1.23.2
+	github.com/prometheus/client_model v0.6.2
 	github.com/spf13/viper v1.21.0
 	github.com/stretchr/testify v1.11.1
+	github.com/tendermint/go-amino v0.16.0
+	github.com/urfave/cli v1.22.17
 )
 
 require (
-	github.com/DSiSc/blockstore v1.1.0 // indirect
 	github.com/DSiSc/contractsManage v1.1.0 // indirect
-	github.com/DSiSc/evm-NG v1.1.0 // indirect
-	github.com/DSiSc/statedb-NG v1.1.0 // indirect
 	github.com/DSiSc/wallet v1.1.0 // indirect
-	github.com/DSiSc/wasm v0.6.0 // indirect
 	github.com/DSiSc/web3go v1.1.0 // indirect
 	github.com/allegro/bigcache v1.2.1 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
@@ -56,8 +60,6 @@ require (
 	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/prometheus/client_golang v1.23.2 // indirect
-	github.com/prometheus/client_model v0.6.2 // indirect
 	github.com/prometheus/common v0.66.1 // indirect
 	github.com/prometheus/procfs v0.16.1 // indirect
 	github.com/rjeczalik/notify v0.9.3 // indirect
@@ -71,10 +73,8 @@ require (
 	github.com/subosito/gotenv v1.6.0 // indirect
 	github.com/syndtr/goleveldb v1.0.0 // indirect
 	github.com/tencentyun/cos-go-sdk-v5 v0.7.71 // indirect
-	github.com/tendermint/go-amino v0.16.0 // indirect
 	github.com/tonnerre/golang-go.crypto v0.0.0-20140219195149-9bbb332f040b // indirect
 	github.com/twitchyliquid64/golang-asm v0.0.0-20190126203739-365674df15fc // indirect
-	github.com/urfave/cli v1.22.17 // indirect
 	go.yaml.in/yaml/v2 v2.4.3 // indirect
 	go.yaml.in/yaml/v3 v3.0.4 // indirect
 	golang.org/x/crypto v0.43.0 // indirect
@@ -0,0 +1,151 @@
+package light
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/common"
+	vcommon "github.com/DSiSc/validator/common"
+	"github.com/DSiSc/validator/tools/signature"
+	"sync"
+)
+
+// Validators is the set of consensus nodes, of which a quorum should sign each header.
+type Validators struct {
+	addresses map[types.Address]bool
+	quorum    int
+}
+
+// NewValidators create validators with addresses, headers need signatures from quorum of them.
+func NewValidators(addresses []types.Address, quorum int) *Validators {
+	validators := &Validators{
+		addresses: make(map[types.Address]bool),
+		quorum:    quorum,
+	}
+	for _, address := range addresses {
+		validators.addresses[address] = true
+	}
+	if validators.quorum < 1 {
+		validators.quorum = 1
+	}
+	return validators
+}
+
+// HeaderChain keep the verified header chain of light node, only the newest headers are cached.
+// Headers are verified by their link to the chain and the signatures of validators. Block signatures of
+// validator/tools/signature are derived from the signer address instead of its private key, so they could
+// be forged by anyone, and light node effectively trusts the peers it syncs from until blocks are signed
+// with real keys.
+type HeaderChain struct {
+	lock       sync.RWMutex
+	current    *types.Header
+	hashes     map[uint64]types.Hash
+	headers    map[uint64]*types.Header
+	validators *Validators
+	cacheLimit uint64
+}
+
+// HeaderHash compute the hash of header, which is the same as the block hash.
+func HeaderHash(header *types.Header) types.Hash {
+	return common.HeaderHash(&types.Block{Header: header})
+}
+
+// NewHeaderChain create a header chain starting from the trusted header with hash, whose successors should
+// be signed by validators.
+func NewHeaderChain(trusted *types.Header, hash types.Hash, validators *Validators, cacheLimit uint64) *HeaderChain {
+	if cacheLimit == 0 {
+		cacheLimit = 1
+	}
+	return &HeaderChain{
+		current:    trusted,
+		hashes:     map[uint64]types.Hash{trusted.Height: hash},
+		headers:    map[uint64]*types.Header{trusted.Height: trusted},
+		validators: validators,
+		cacheLimit: cacheLimit,
+	}
+}
+
+// CurrentHeader return the newest verified header and its hash.
+func (hc *HeaderChain) CurrentHeader() (*types.Header, types.Hash) {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+	return hc.current, hc.hashes[hc.current.Height]
+}
+
+// GetHeaderByHeight return the verified header with height and its hash,
+// headers which have been evicted from cache are reported as not found.
+func (hc *HeaderChain) GetHeaderByHeight(height uint64) (*types.Header, types.Hash, error) {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+	header, ok := hc.headers[height]
+	if !ok {
+		return nil, types.Hash{}, fmt.Errorf("header with height %d not found", height)
+	}
+	return header, hc.hashes[height], nil
+}
+
+// verify check header is the direct successor of current header, and is signed by a quorum of validators.
+func (hc *HeaderChain) verify(header *types.Header) error {
+	if header.Height != hc.current.Height+1 {
+		return fmt.Errorf("expect header with height %d, but got %d", hc.current.Height+1, header.Height)
+	}
+	if header.PrevBlockHash != hc.hashes[hc.current.Height] {
+		return fmt.Errorf("header %d does not link to current header %x", header.Height, hc.hashes[hc.current.Height])
+	}
+	if header.ChainID != hc.current.ChainID {
+		return fmt.Errorf("header %d has chain id %d, expect %d", header.Height, header.ChainID, hc.current.ChainID)
+	}
+	if header.Timestamp < hc.current.Timestamp {
+		return fmt.Errorf("header %d is older than its parent", header.Height)
+	}
+	return hc.verifySignatures(header)
+}
+
+// verifySignatures check the digest of header, and count the distinct validators signing it.
+func (hc *HeaderChain) verifySignatures(header *types.Header) error {
+	unsigned := *header
+	unsigned.MixDigest = types.Hash{}
+	digest := vcommon.HeaderDigest(&unsigned)
+	if digest != header.MixDigest {
+		return fmt.Errorf("header %d has digest %x, expect %x", header.Height, header.MixDigest, digest)
+	}
+	signers := make(map[types.Address]bool)
+	for _, sign := range header.SigData {
+		signer, err := signature.Verify(digest, sign)
+		if nil != err || !hc.validators.addresses[signer] {
+			continue
+		}
+		signers[signer] = true
+	}
+	if len(signers) < hc.validators.quorum {
+		return fmt.Errorf("header %d is signed by %d validators, expect %d", header.Height, len(signers), hc.validators.quorum)
+	}
+	return nil
+}
+
+// InsertHeaders verify headers one by one and append them to the chain. Headers not higher than
+// current header are skipped, and insertion stops at the first invalid one.
+// It returns the number of inserted headers.
+func (hc *HeaderChain) InsertHeaders(headers []*types.Header) (int, error) {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+	inserted := 0
+	for _, header := range headers {
+		if header.Height <= hc.current.Height {
+			continue
+		}
+		if err := hc.verify(header); nil != err {
+			log.Warn("Verify header failed with error %v.", err)
+			return inserted, err
+		}
+		hc.hashes[header.Height] = HeaderHash(header)
+		hc.headers[header.Height] = header
+		hc.current = header
+		if header.Height >= hc.cacheLimit {
+			delete(hc.hashes, header.Height-hc.cacheLimit)
+			delete(hc.headers, header.Height-hc.cacheLimit)
+		}
+		inserted++
+	}
+	return inserted, nil
+}
@@ -0,0 +1,135 @@
+package light
+
+import (
+	"github.com/DSiSc/craft/types"
+	vcommon "github.com/DSiSc/validator/common"
+	"github.com/DSiSc/validator/tools"
+	"github.com/DSiSc/validator/tools/account"
+	"github.com/DSiSc/validator/tools/signature"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var mockValidators = []types.Address{
+	tools.HexToAddress("333c3310824b7c685133f2bedb2ca4b8b4df633d"),
+	tools.HexToAddress("343c3310824b7c685133f2bedb2ca4b8b4df633d"),
+}
+
+// signHeader set the digest of header and append the signatures of signers.
+func signHeader(header *types.Header, signers ...types.Address) {
+	header.MixDigest = types.Hash{}
+	header.MixDigest = vcommon.HeaderDigest(header)
+	header.SigData = nil
+	for _, signer := range signers {
+		sign, _ := signature.Sign(&account.Account{Address: signer}, header.MixDigest[:])
+		header.SigData = append(header.SigData, sign)
+	}
+}
+
+// mockHeaders return count headers following parent, signed by all mock validators.
+func mockHeaders(parent *types.Header, count int) []*types.Header {
+	headers := make([]*types.Header, 0, count)
+	for i := 0; i < count; i++ {
+		header := &types.Header{
+			ChainID:       parent.ChainID,
+			PrevBlockHash: HeaderHash(parent),
+			Height:        parent.Height + 1,
+			Timestamp:     parent.Timestamp + 1,
+			StateRoot:     types.Hash{byte(parent.Height + 1)},
+		}
+		signHeader(header, mockValidators...)
+		headers = append(headers, header)
+		parent = header
+	}
+	return headers
+}
+
+func mockChain(cacheLimit uint64) (*HeaderChain, *types.Header) {
+	genesis := &types.Header{
+		ChainID: 1,
+	}
+	return NewHeaderChain(genesis, HeaderHash(genesis), NewValidators(mockValidators, len(mockValidators)), cacheLimit), genesis
+}
+
+func TestHeaderChain_InsertHeaders(t *testing.T) {
+	assert := assert.New(t)
+	chain, genesis := mockChain(1024)
+	headers := mockHeaders(genesis, 3)
+	inserted, err := chain.InsertHeaders(headers)
+	assert.Nil(err)
+	assert.Equal(3, inserted)
+	current, hash := chain.CurrentHeader()
+	assert.Equal(uint64(3), current.Height)
+	assert.Equal(HeaderHash(headers[2]), hash)
+
+	// known headers are skipped
+	inserted, err = chain.InsertHeaders(append(headers, mockHeaders(headers[2], 1)...))
+	assert.Nil(err)
+	assert.Equal(1, inserted)
+
+	header, hash, err := chain.GetHeaderByHeight(2)
+	assert.Nil(err)
+	assert.Equal(headers[1], header)
+	assert.Equal(HeaderHash(headers[1]), hash)
+	_, _, err = chain.GetHeaderByHeight(5)
+	assert.NotNil(err)
+}
+
+func TestHeaderChain_InsertInvalidHeaders(t *testing.T) {
+	assert := assert.New(t)
+	chain, genesis := mockChain(1024)
+	headers := mockHeaders(genesis, 3)
+	headers[1].PrevBlockHash = types.Hash{0x1}
+	inserted, err := chain.InsertHeaders(headers)
+	assert.NotNil(err)
+	assert.Equal(1, inserted)
+
+	headers = mockHeaders(headers[0], 2)
+	headers[0].ChainID = 2
+	_, err = chain.InsertHeaders(headers)
+	assert.NotNil(err)
+
+	headers = mockHeaders(genesis, 3)
+	_, err = chain.InsertHeaders(headers[2:])
+	assert.NotNil(err)
+	current, _ := chain.CurrentHeader()
+	assert.Equal(uint64(1), current.Height)
+}
+
+func TestHeaderChain_InsertUnsignedHeaders(t *testing.T) {
+	assert := assert.New(t)
+	chain, genesis := mockChain(1024)
+	headers := mockHeaders(genesis, 2)
+
+	// header changed after signed
+	headers[0].StateRoot = types.Hash{0xff}
+	_, err := chain.InsertHeaders(headers)
+	assert.NotNil(err)
+
+	// header signed by too few validators, or by nodes out of validators
+	headers = mockHeaders(genesis, 2)
+	signHeader(headers[0], mockValidators[0])
+	_, err = chain.InsertHeaders(headers)
+	assert.NotNil(err)
+	signHeader(headers[0], mockValidators[0], mockValidators[0], tools.HexToAddress("353c3310824b7c685133f2bedb2ca4b8b4df633d"))
+	_, err = chain.InsertHeaders(headers)
+	assert.NotNil(err)
+	current, _ := chain.CurrentHeader()
+	assert.Equal(uint64(0), current.Height)
+
+	signHeader(headers[0], mockValidators...)
+	inserted, err := chain.InsertHeaders(headers)
+	assert.Nil(err)
+	assert.Equal(2, inserted)
+}
+
+func TestHeaderChain_CacheLimit(t *testing.T) {
+	assert := assert.New(t)
+	chain, genesis := mockChain(2)
+	_, err := chain.InsertHeaders(mockHeaders(genesis, 3))
+	assert.Nil(err)
+	_, _, err = chain.GetHeaderByHeight(1)
+	assert.NotNil(err)
+	_, _, err = chain.GetHeaderByHeight(2)
+	assert.Nil(err)
+}
@@ -0,0 +1,123 @@
+package light
+
+import (
+	"errors"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/p2p"
+	"github.com/DSiSc/p2p/message"
+	"sync"
+	"time"
+)
+
+// interval to gather new headers when no header arrived
+const headerSyncInterval = 5 * time.Second
+
+// HeaderSyncer sync headers from full peers, which serve header requests through their block syncer.
+// Requests for blocks and headers from other peers are ignored, as light node keeps no blocks.
+type HeaderSyncer struct {
+	lock        sync.Mutex
+	p2p         p2p.P2PAPI
+	chain       *HeaderChain
+	eventCenter types.EventCenter
+	subscriber  types.Subscriber
+	syncChan    chan interface{}
+	quitChan    chan interface{}
+	isRunning   bool
+}
+
+// NewHeaderSyncer create a header syncer which appends headers gathered from p2p to chain.
+func NewHeaderSyncer(p2p p2p.P2PAPI, chain *HeaderChain, eventCenter types.EventCenter) *HeaderSyncer {
+	return &HeaderSyncer{
+		p2p:         p2p,
+		chain:       chain,
+		eventCenter: eventCenter,
+		syncChan:    make(chan interface{}, 1),
+		quitChan:    make(chan interface{}),
+	}
+}
+
+// Start start header syncer
+func (hs *HeaderSyncer) Start() error {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if hs.isRunning {
+		return errors.New("header syncer already started")
+	}
+	hs.isRunning = true
+	hs.subscriber = hs.eventCenter.Subscribe(types.EventAddPeer, func(interface{}) {
+		hs.triggerSync()
+	})
+	go hs.reqHandler()
+	go hs.recvHandler()
+	return nil
+}
+
+// Stop stop header syncer
+func (hs *HeaderSyncer) Stop() {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if !hs.isRunning {
+		return
+	}
+	hs.isRunning = false
+	hs.eventCenter.UnSubscribe(types.EventAddPeer, hs.subscriber)
+	close(hs.quitChan)
+}
+
+func (hs *HeaderSyncer) triggerSync() {
+	select {
+	case hs.syncChan <- nil:
+	default:
+	}
+}
+
+// request headers following current header from all peers.
+func (hs *HeaderSyncer) reqHandler() {
+	timer := time.NewTicker(headerSyncInterval)
+	defer timer.Stop()
+	for {
+		_, hash := hs.chain.CurrentHeader()
+		err := hs.p2p.Gather(func(peerState uint64) bool {
+			return true
+		}, &message.BlockHeaderReq{
+			Len:      message.MAX_BLOCK_HEADER_NUM,
+			HashStop: hash,
+		})
+		if nil != err {
+			log.Warn("Gather headers following %x failed with error %v.", hash, err)
+		}
+		select {
+		case <-hs.syncChan:
+		case <-timer.C:
+		case <-hs.quitChan:
+			return
+		}
+	}
+}
+
+func (hs *HeaderSyncer) recvHandler() {
+	msgChan := hs.p2p.MessageChan()
+	for {
+		select {
+		case msg := <-msgChan:
+			switch payload := msg.Payload.(type) {
+			case *message.BlockHeaders:
+				inserted, err := hs.chain.InsertHeaders(payload.Headers)
+				if nil != err {
+					log.Warn("Insert headers from peer %s failed with error %v.", msg.From.ToString(), err)
+				}
+				if inserted > 0 {
+					current, _ := hs.chain.CurrentHeader()
+					log.Info("Sync %d headers, current height %d.", inserted, current.Height)
+					// there may be more headers, ask for them right now.
+					hs.triggerSync()
+				}
+			default:
+				log.Debug("Light node ignore message %v.", msg.Payload.MsgType())
+			}
+		case <-hs.quitChan:
+			return
+		}
+	}
+}
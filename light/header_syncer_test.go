@@ -0,0 +1,71 @@
+package light
+
+import (
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/p2p"
+	"github.com/DSiSc/p2p/common"
+	"github.com/DSiSc/p2p/message"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockP2P struct {
+	msgChan   chan *p2p.InternalMsg
+	gatherReq chan message.Message
+}
+
+func newMockP2P() *mockP2P {
+	return &mockP2P{
+		msgChan:   make(chan *p2p.InternalMsg),
+		gatherReq: make(chan message.Message, 10),
+	}
+}
+
+func (m *mockP2P) Start() error {
+	return nil
+}
+
+func (m *mockP2P) Stop() {}
+
+func (m *mockP2P) BroadCast(msg message.Message) {}
+
+func (m *mockP2P) SendMsg(peerAddr *common.NetAddress, msg message.Message) error {
+	return nil
+}
+
+func (m *mockP2P) Gather(peerFilter p2p.PeerFilter, reqMsg message.Message) error {
+	m.gatherReq <- reqMsg
+	return nil
+}
+
+func (m *mockP2P) MessageChan() <-chan *p2p.InternalMsg {
+	return m.msgChan
+}
+
+func TestHeaderSyncer(t *testing.T) {
+	assert := assert.New(t)
+	chain, genesis := mockChain(1024)
+	mockP2P := newMockP2P()
+	syncer := NewHeaderSyncer(mockP2P, chain, events.NewEvent())
+	assert.Nil(syncer.Start())
+	assert.NotNil(syncer.Start())
+	defer syncer.Stop()
+
+	req := (<-mockP2P.gatherReq).(*message.BlockHeaderReq)
+	assert.Equal(HeaderHash(genesis), req.HashStop)
+
+	headers := mockHeaders(genesis, 2)
+	mockP2P.msgChan <- &p2p.InternalMsg{
+		From:    &common.NetAddress{},
+		Payload: &message.BlockHeaders{Headers: headers},
+	}
+	select {
+	case msg := <-mockP2P.gatherReq:
+		assert.Equal(HeaderHash(headers[1]), msg.(*message.BlockHeaderReq).HashStop)
+	case <-time.After(time.Second):
+		assert.Fail("header syncer did not request following headers")
+	}
+	current, _ := chain.CurrentHeader()
+	assert.Equal(uint64(2), current.Height)
+}
@@ -0,0 +1,66 @@
+package light
+
+import (
+	cmn "github.com/DSiSc/apigateway/common"
+	apitypes "github.com/DSiSc/apigateway/core/types"
+	rpcserver "github.com/DSiSc/apigateway/rpc/lib/server"
+	"github.com/DSiSc/craft/types"
+)
+
+// Routes return rpc functions served by light node. They share the names and params of the
+// full node apis, but block numbers are limited to the headers cached in chain.
+func Routes(chain *HeaderChain, client *StateClient) map[string]*rpcserver.RPCFunc {
+	api := &lightAPI{
+		chain:  chain,
+		client: client,
+	}
+	return map[string]*rpcserver.RPCFunc{
+		"eth_blockNumber":         rpcserver.NewRPCFunc(api.BlockNumber, ""),
+		"eth_getBalance":          rpcserver.NewRPCFunc(api.GetBalance, "address, blockNr"),
+		"eth_getTransactionCount": rpcserver.NewRPCFunc(api.GetTransactionCount, "address, blockNr"),
+		"eth_getCode":             rpcserver.NewRPCFunc(api.GetCode, "address, blockNr"),
+	}
+}
+
+type lightAPI struct {
+	chain  *HeaderChain
+	client *StateClient
+}
+
+// height convert block number to the height of a verified header, pending is treated as latest.
+func (api *lightAPI) height(blockNr apitypes.BlockNumber) uint64 {
+	if blockNr == apitypes.LatestBlockNumber || blockNr == apitypes.PendingBlockNumber {
+		current, _ := api.chain.CurrentHeader()
+		return current.Height
+	}
+	return blockNr.Touint64()
+}
+
+func (api *lightAPI) BlockNumber() (*cmn.Uint64, error) {
+	current, _ := api.chain.CurrentHeader()
+	return cmn.NewUint64(current.Height), nil
+}
+
+func (api *lightAPI) GetBalance(address apitypes.Address, blockNr apitypes.BlockNumber) (*cmn.Big, error) {
+	balance, err := api.client.GetBalance(types.Address(address), api.height(blockNr))
+	if nil != err {
+		return nil, err
+	}
+	return cmn.NewBig(balance), nil
+}
+
+func (api *lightAPI) GetTransactionCount(address apitypes.Address, blockNr apitypes.BlockNumber) (*cmn.Uint64, error) {
+	nonce, err := api.client.GetNonce(types.Address(address), api.height(blockNr))
+	if nil != err {
+		return nil, err
+	}
+	return cmn.NewUint64(nonce), nil
+}
+
+func (api *lightAPI) GetCode(address apitypes.Address, blockNr apitypes.BlockNumber) (*cmn.Bytes, error) {
+	code, err := api.client.GetCode(types.Address(address), api.height(blockNr))
+	if nil != err {
+		return nil, err
+	}
+	return cmn.NewBytes(code), nil
+}
@@ -0,0 +1,171 @@
+package light
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// timeout of a single request to full peer
+const stateRequestTimeout = 10 * time.Second
+
+// StateClient fetch account state on demand from the json rpc of full peers. Before trusting a peer,
+// it checks the peer has the same block hash and state root as the verified header at that height.
+// Full peers do not return state proofs, so the answer itself is trusted once the check passed.
+type StateClient struct {
+	peers  []string
+	chain  *HeaderChain
+	client *http.Client
+	nextId uint64
+}
+
+// NewStateClient create a state client which query full peers with rpc addresses in peers.
+func NewStateClient(peers []string, chain *HeaderChain) *StateClient {
+	return &StateClient{
+		peers: peers,
+		chain: chain,
+		client: &http.Client{
+			Timeout: stateRequestTimeout,
+		},
+	}
+}
+
+type rpcRequest struct {
+	JsonRpc string        `json:"jsonrpc"`
+	Id      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcBlock struct {
+	Hash      string `json:"hash"`
+	StateRoot string `json:"stateRoot"`
+}
+
+func (sc *StateClient) call(peer string, method string, result interface{}, params ...interface{}) error {
+	body, err := json.Marshal(&rpcRequest{
+		JsonRpc: "2.0",
+		Id:      strconv.FormatUint(atomic.AddUint64(&sc.nextId, 1), 10),
+		Method:  method,
+		Params:  params,
+	})
+	if nil != err {
+		return err
+	}
+	resp, err := sc.client.Post(peer, "application/json", bytes.NewReader(body))
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	var response rpcResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); nil != err {
+		return fmt.Errorf("decode response of %s failed: %v", method, err)
+	}
+	if nil != response.Error {
+		return fmt.Errorf("%s failed: %s %s", method, response.Error.Message, response.Error.Data)
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// checkPeer check the block of peer at height matches the verified header.
+func (sc *StateClient) checkPeer(peer string, header *types.Header, hash types.Hash) error {
+	var block rpcBlock
+	if err := sc.call(peer, "eth_getBlockByNumber", &block, encodeUint(header.Height), false); nil != err {
+		return err
+	}
+	if !strings.EqualFold(block.Hash, encodeHash(hash)) {
+		return fmt.Errorf("peer block %d has hash %s, expect %s", header.Height, block.Hash, encodeHash(hash))
+	}
+	if !strings.EqualFold(block.StateRoot, encodeHash(header.StateRoot)) {
+		return fmt.Errorf("peer block %d has state root %s, expect %s", header.Height, block.StateRoot, encodeHash(header.StateRoot))
+	}
+	return nil
+}
+
+// query call method on the first full peer which passes the check at height,
+// the address and block number are appended to params.
+func (sc *StateClient) query(method string, address types.Address, height uint64, result interface{}) error {
+	header, hash, err := sc.chain.GetHeaderByHeight(height)
+	if nil != err {
+		return err
+	}
+	if len(sc.peers) == 0 {
+		return errors.New("no full peer configured")
+	}
+	var errs []error
+	for _, peer := range sc.peers {
+		if err = sc.checkPeer(peer, header, hash); nil == err {
+			err = sc.call(peer, method, result, encodeAddress(address), encodeUint(height))
+		}
+		if nil == err {
+			return nil
+		}
+		log.Warn("Query %s from full peer %s failed with error %v.", method, peer, err)
+		errs = append(errs, fmt.Errorf("peer %s: %v", peer, err))
+	}
+	return errors.Join(errs...)
+}
+
+// GetBalance return the balance of address at height.
+func (sc *StateClient) GetBalance(address types.Address, height uint64) (*big.Int, error) {
+	var balance string
+	if err := sc.query("eth_getBalance", address, height, &balance); nil != err {
+		return nil, err
+	}
+	value, ok := new(big.Int).SetString(strings.TrimPrefix(balance, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance %s", balance)
+	}
+	return value, nil
+}
+
+// GetNonce return the nonce of address at height.
+func (sc *StateClient) GetNonce(address types.Address, height uint64) (uint64, error) {
+	var nonce string
+	if err := sc.query("eth_getTransactionCount", address, height, &nonce); nil != err {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimPrefix(nonce, "0x"), 16, 64)
+}
+
+// GetCode return the contract code of address at height.
+func (sc *StateClient) GetCode(address types.Address, height uint64) ([]byte, error) {
+	var code string
+	if err := sc.query("eth_getCode", address, height, &code); nil != err {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimPrefix(code, "0x"))
+}
+
+func encodeUint(value uint64) string {
+	return fmt.Sprintf("0x%x", value)
+}
+
+func encodeHash(hash types.Hash) string {
+	return fmt.Sprintf("0x%x", hash[:])
+}
+
+func encodeAddress(address types.Address) string {
+	return fmt.Sprintf("0x%x", address[:])
+}
@@ -0,0 +1,87 @@
+package light
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DSiSc/craft/types"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockFullPeer serve the json rpc of a full node whose chain ends with header.
+func mockFullPeer(header *types.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		var result interface{}
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			result = map[string]string{
+				"hash":      encodeHash(HeaderHash(header)),
+				"stateRoot": encodeHash(header.StateRoot),
+			}
+		case "eth_getBalance":
+			result = "0x64"
+		case "eth_getTransactionCount":
+			result = "0x2"
+		case "eth_getCode":
+			result = "0x6080"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.Id,
+			"result":  result,
+		})
+	}))
+}
+
+func TestStateClient(t *testing.T) {
+	assert := assert.New(t)
+	chain, genesis := mockChain(1024)
+	headers := mockHeaders(genesis, 2)
+	_, err := chain.InsertHeaders(headers)
+	assert.Nil(err)
+	peer := mockFullPeer(headers[1])
+	defer peer.Close()
+
+	client := NewStateClient([]string{peer.URL}, chain)
+	balance, err := client.GetBalance(types.Address{0x1}, 2)
+	assert.Nil(err)
+	assert.Equal(big.NewInt(100), balance)
+	nonce, err := client.GetNonce(types.Address{0x1}, 2)
+	assert.Nil(err)
+	assert.Equal(uint64(2), nonce)
+	code, err := client.GetCode(types.Address{0x1}, 2)
+	assert.Nil(err)
+	assert.Equal([]byte{0x60, 0x80}, code)
+
+	// header not verified yet
+	_, err = client.GetBalance(types.Address{0x1}, 3)
+	assert.NotNil(err)
+}
+
+func TestStateClient_PeerOnOtherChain(t *testing.T) {
+	assert := assert.New(t)
+	chain, genesis := mockChain(1024)
+	headers := mockHeaders(genesis, 2)
+	_, err := chain.InsertHeaders(headers)
+	assert.Nil(err)
+	fork := mockHeaders(headers[0], 1)[0]
+	fork.StateRoot = types.Hash{0xff}
+	badPeer := mockFullPeer(fork)
+	defer badPeer.Close()
+
+	client := NewStateClient([]string{badPeer.URL}, chain)
+	_, err = client.GetBalance(types.Address{0x1}, 2)
+	assert.NotNil(err)
+
+	goodPeer := mockFullPeer(headers[1])
+	defer goodPeer.Close()
+	client = NewStateClient([]string{badPeer.URL, goodPeer.URL}, chain)
+	balance, err := client.GetBalance(types.Address{0x1}, 2)
+	assert.Nil(err)
+	assert.Equal(fmt.Sprint(100), balance.String())
+}
@@ -0,0 +1,411 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	tmlog "github.com/DSiSc/apigateway/log"
+	rpcserver "github.com/DSiSc/apigateway/rpc/lib/server"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/p2p"
+	p2pCommon "github.com/DSiSc/p2p/common"
+	"github.com/tendermint/go-amino"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// names of p2p instances in admin rpc
+var adminP2PNames = map[string]string{
+	"blockSyncer": config.BlockSyncerP2P,
+	"block":       config.BlockP2P,
+	"tx":          config.TxP2P,
+}
+
+var logLevelNames = map[log.Level]string{
+	log.DebugLevel: "debug",
+	log.InfoLevel:  "info",
+	log.WarnLevel:  "warn",
+	log.ErrorLevel: "error",
+	log.FatalLevel: "fatal",
+	log.PanicLevel: "panic",
+}
+
+var eventTypeNames = map[types.EventType]string{
+	types.EventBlockCommitted:    "blockCommitted",
+	types.EventBlockCommitFailed: "blockCommitFailed",
+	types.EventBlockVerifyFailed: "blockVerifyFailed",
+	types.EventBlockExisted:      "blockExisted",
+	types.EventConsensusFailed:   "consensusFailed",
+	types.EventBlockWritten:      "blockWritten",
+	types.EventBlockWriteFailed:  "blockWriteFailed",
+	types.EventTxVerifySucceeded: "txVerifySucceeded",
+	types.EventTxVerifyFailed:    "txVerifyFailed",
+	types.EventMasterChange:      "masterChange",
+	types.EventOnline:            "online",
+	types.EventBlockWithoutTxs:   "blockWithoutTxs",
+	types.EventRemovePeer:        "removePeer",
+	types.EventAddPeer:           "addPeer",
+	types.EventBroadCastMsg:      "broadCastMsg",
+	types.EventRecvNewMsg:        "recvNewMsg",
+	types.EventAddTxToTxPool:     "addTxToTxPool",
+}
+
+// AdminPeers is the peers of a p2p instance.
+type AdminPeers struct {
+	P2P        string   `json:"p2p"`
+	Persistent []string `json:"persistent"`
+	Connected  []string `json:"connected"`
+}
+
+// AdminTx is the summary of a transaction in txpool.
+type AdminTx struct {
+	Hash     string `json:"hash"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Nonce    uint64 `json:"nonce"`
+	Value    string `json:"value"`
+	GasLimit uint64 `json:"gas"`
+	GasPrice string `json:"gasPrice"`
+}
+
+// AdminRoundStatus is the state of block production.
+type AdminRoundStatus struct {
+	Round   uint64 `json:"round"`
+	State   string `json:"state"`
+	Since   string `json:"since"`
+	Delay   string `json:"delay"`
+	Average string `json:"average"`
+	Timeout string `json:"timeout"`
+	Pending int64  `json:"pending"`
+	Paused  bool   `json:"paused"`
+}
+
+// adminRoutes return privileged rpc functions, which are only served by admin gateway.
+func (instance *Node) adminRoutes() map[string]*rpcserver.RPCFunc {
+	return map[string]*rpcserver.RPCFunc{
+		"admin_restart":          rpcserver.NewRPCFunc(instance.adminRestart, ""),
+		"admin_peers":            rpcserver.NewRPCFunc(instance.adminPeers, "p2p"),
+		"admin_addPeer":          rpcserver.NewRPCFunc(instance.adminAddPeer, "p2p,address"),
+		"admin_removePeer":       rpcserver.NewRPCFunc(instance.adminRemovePeer, "p2p,address"),
+		"admin_logLevels":        rpcserver.NewRPCFunc(adminLogLevels, ""),
+		"admin_setLogLevel":      rpcserver.NewRPCFunc(adminSetLogLevel, "appender,level"),
+		"admin_pauseProduction":  rpcserver.NewRPCFunc(instance.adminPauseProduction, ""),
+		"admin_resumeProduction": rpcserver.NewRPCFunc(instance.adminResumeProduction, ""),
+		"admin_roundStatus":      rpcserver.NewRPCFunc(instance.adminRoundStatus, ""),
+		"admin_txpool":           rpcserver.NewRPCFunc(instance.adminTxpool, ""),
+		"admin_subscribers":      rpcserver.NewRPCFunc(instance.adminSubscribers, ""),
+		"admin_config":           rpcserver.NewRPCFunc(instance.adminConfig, ""),
+		"admin_systemContracts":  rpcserver.NewRPCFunc(instance.adminSystemContracts, ""),
+	}
+}
+
+// adminRestart restart node service in process.
+func (instance *Node) adminRestart() (bool, error) {
+	log.Warn("Receive admin request to restart node service.")
+	if err := instance.Restart(); nil != err {
+		return false, err
+	}
+	return true, nil
+}
+
+func splitPeers(peers string) []string {
+	list := make([]string, 0)
+	for _, peer := range strings.Split(peers, ",") {
+		if peer = strings.TrimSpace(peer); common.BlankString != peer {
+			list = append(list, peer)
+		}
+	}
+	return list
+}
+
+// runningP2P return the p2p instance with config name, which is nil if node has no such instance.
+func (instance *Node) runningP2P(name string) p2p.P2PAPI {
+	switch name {
+	case config.BlockSyncerP2P:
+		return instance.blockSyncerP2P
+	case config.BlockP2P:
+		return instance.blockP2P
+	default:
+		return instance.txP2P
+	}
+}
+
+func (instance *Node) adminPeers(p2pName string) (*AdminPeers, error) {
+	name, ok := adminP2PNames[p2pName]
+	if !ok {
+		return nil, fmt.Errorf("unknown p2p %s", p2pName)
+	}
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	peers := &AdminPeers{
+		P2P:       p2pName,
+		Connected: make([]string, 0),
+	}
+	if p2pConf, ok := instance.config.P2PConf[name]; ok {
+		peers.Persistent = splitPeers(p2pConf.PersistentPeers)
+	}
+	// only p2p created from config could tell its peers.
+	if running, ok := instance.runningP2P(name).(*p2p.P2P); ok && nil != running {
+		for _, peer := range running.GetPeers() {
+			peers.Connected = append(peers.Connected, peer.GetAddr().ToString())
+		}
+	}
+	return peers, nil
+}
+
+// updatePeers change persistent peers in config of p2p, and restart node to apply it,
+// as a running p2p connects to persistent peers only on start. The change is rolled back if restart failed.
+func (instance *Node) updatePeers(p2pName string, address string, update func(peers []string) ([]string, error)) (*AdminPeers, error) {
+	name, ok := adminP2PNames[p2pName]
+	if !ok {
+		return nil, fmt.Errorf("unknown p2p %s", p2pName)
+	}
+	if _, err := p2pCommon.ParseNetAddress(address); nil != err {
+		return nil, fmt.Errorf("invalid peer address %s: %v", address, err)
+	}
+	instance.lock.Lock()
+	if _, ok := instance.injectedP2Ps[name]; ok {
+		instance.lock.Unlock()
+		return nil, fmt.Errorf("p2p %s is injected, its peers could not be changed", p2pName)
+	}
+	// check restart up front, so that peers are not changed without being applied.
+	if !instance.isRunning {
+		instance.lock.Unlock()
+		return nil, errors.New("node service is not running")
+	}
+	if err := instance.restartable(); nil != err {
+		instance.lock.Unlock()
+		return nil, err
+	}
+	p2pConf, ok := instance.config.P2PConf[name]
+	if !ok {
+		instance.lock.Unlock()
+		return nil, fmt.Errorf("p2p %s not configured", p2pName)
+	}
+	peers, err := update(splitPeers(p2pConf.PersistentPeers))
+	if nil != err {
+		instance.lock.Unlock()
+		return nil, err
+	}
+	// running p2p holds the old config, so replace it instead of modifying.
+	newConf := *p2pConf
+	newConf.PersistentPeers = strings.Join(peers, ",")
+	instance.config.P2PConf[name] = &newConf
+	instance.lock.Unlock()
+	log.Warn("Persistent peers of p2p %s changed to %s, restart node to apply it.", p2pName, newConf.PersistentPeers)
+	if err = instance.Restart(); nil != err {
+		instance.lock.Lock()
+		if instance.config.P2PConf[name] == &newConf {
+			instance.config.P2PConf[name] = p2pConf
+		}
+		instance.lock.Unlock()
+		return nil, fmt.Errorf("peers not changed, as restart failed: %v", err)
+	}
+	return instance.adminPeers(p2pName)
+}
+
+// adminAddPeer add a persistent peer to p2p.
+func (instance *Node) adminAddPeer(p2pName string, address string) (*AdminPeers, error) {
+	return instance.updatePeers(p2pName, address, func(peers []string) ([]string, error) {
+		for _, peer := range peers {
+			if peer == address {
+				return nil, fmt.Errorf("peer %s already exists", address)
+			}
+		}
+		return append(peers, address), nil
+	})
+}
+
+// adminRemovePeer remove a persistent peer from p2p. The peer is no longer kept connected,
+// while it may still be connected as a normal peer found in address book.
+func (instance *Node) adminRemovePeer(p2pName string, address string) (*AdminPeers, error) {
+	return instance.updatePeers(p2pName, address, func(peers []string) ([]string, error) {
+		for i, peer := range peers {
+			if peer == address {
+				return append(peers[:i], peers[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("peer %s not found", address)
+	})
+}
+
+// appenderName return the short name of log appender, such as console for logging.console.
+func appenderName(name string) string {
+	return strings.TrimPrefix(name, "logging.")
+}
+
+func adminLogLevels() (map[string]string, error) {
+	levels := make(map[string]string)
+	for name, appender := range log.GetGlobalConfig().Appenders {
+		levels[appenderName(name)] = logLevelNames[appender.LogLevel]
+	}
+	return levels, nil
+}
+
+// adminSetLogLevel change log level of appender, or all appenders if appender is empty.
+func adminSetLogLevel(appender string, level string) (map[string]string, error) {
+	var newLevel log.Level
+	found := false
+	for value, name := range logLevelNames {
+		if name == strings.ToLower(level) {
+			newLevel = value
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown log level %s", level)
+	}
+	logConf := log.GetGlobalConfig()
+	changed := false
+	for name, app := range logConf.Appenders {
+		if common.BlankString == appender || appender == name || appender == appenderName(name) {
+			app.LogLevel = newLevel
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, fmt.Errorf("unknown log appender %s", appender)
+	}
+	// global level filters logs before appenders.
+	if newLevel < log.GetGlobalLogLevel() {
+		log.SetGlobalLogLevel(newLevel)
+	}
+	log.SetAppenders(logConf.Appenders)
+	log.Warn("Log level of appender %s changed to %s.", appender, level)
+	return adminLogLevels()
+}
+
+func (instance *Node) adminPauseProduction() (bool, error) {
+	if err := instance.PauseProduction(); nil != err {
+		return false, err
+	}
+	return true, nil
+}
+
+func (instance *Node) adminResumeProduction() (bool, error) {
+	if err := instance.ResumeProduction(); nil != err {
+		return false, err
+	}
+	return true, nil
+}
+
+func (instance *Node) adminRoundStatus() (*AdminRoundStatus, error) {
+	if common.ConsensusNode != instance.config.NodeType {
+		return nil, errors.New("block production is only run by consensus node")
+	}
+	status := instance.RoundStatus()
+	return &AdminRoundStatus{
+		Round:   status.Round,
+		State:   status.State.String(),
+		Since:   status.Since.Format(time.RFC3339Nano),
+		Delay:   status.Delay.String(),
+		Average: status.Average.String(),
+		Timeout: status.Timeout.String(),
+		Pending: status.Pending,
+		Paused:  instance.production.paused(),
+	}, nil
+}
+
+// adminConfig return the running node config with the source of each value, secrets are redacted.
+func (instance *Node) adminConfig() ([]config.ConfigValue, error) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	return config.DescribeNodeConfig(instance.config), nil
+}
+
+// adminSystemContracts return the addresses of system contracts deployed by genesis block.
+func (instance *Node) adminSystemContracts() (map[string]string, error) {
+	contracts := make(map[string]string, len(instance.systemContracts))
+	for name, address := range instance.systemContracts {
+		contracts[name] = fmt.Sprintf("0x%x", address)
+	}
+	return contracts, nil
+}
+
+// adminTxpool dump the executable transactions in txpool, which are the ones to be packed into next block.
+func (instance *Node) adminTxpool() ([]*AdminTx, error) {
+	instance.lock.Lock()
+	pool := instance.txpool
+	instance.lock.Unlock()
+	if nil == pool {
+		return nil, errors.New("node keeps no txpool")
+	}
+	txs := make([]*AdminTx, 0)
+	for _, tx := range pool.GetTxs() {
+		adminTx := &AdminTx{
+			Hash:     fmt.Sprintf("0x%x", common.TxHash(tx)),
+			Nonce:    tx.Data.AccountNonce,
+			Value:    tx.Data.Amount.String(),
+			GasLimit: tx.Data.GasLimit,
+			GasPrice: tx.Data.Price.String(),
+		}
+		if nil != tx.Data.From {
+			adminTx.From = fmt.Sprintf("0x%x", *tx.Data.From)
+		}
+		if nil != tx.Data.Recipient {
+			adminTx.To = fmt.Sprintf("0x%x", *tx.Data.Recipient)
+		}
+		txs = append(txs, adminTx)
+	}
+	return txs, nil
+}
+
+// adminSubscribers list the functions subscribed to each event type of event center.
+func (instance *Node) adminSubscribers() (map[string][]string, error) {
+	center, ok := instance.eventCenter.(*events.Event)
+	if !ok {
+		return nil, errors.New("event center does not support listing subscribers")
+	}
+	subscribers := make(map[string][]string)
+	for eventType, eventFuncs := range center.Subscriptions() {
+		name, ok := eventTypeNames[eventType]
+		if !ok {
+			name = fmt.Sprintf("event%d", eventType)
+		}
+		funcNames := make([]string, 0, len(eventFuncs))
+		for _, eventFunc := range eventFuncs {
+			funcNames = append(funcNames, runtime.FuncForPC(reflect.ValueOf(eventFunc).Pointer()).Name())
+		}
+		sort.Strings(funcNames)
+		subscribers[name] = funcNames
+	}
+	return subscribers, nil
+}
+
+// startAdmin serve admin rpc on a separate listener, as it must keep working across restarts.
+func (instance *Node) startAdmin() error {
+	if common.BlankString == instance.config.AdminGatewayAddr {
+		log.Info("Admin gateway not configured, admin rpc is disabled.")
+		return nil
+	}
+	listener, err := startRPCServer(instance.config.AdminGatewayAddr, instance.adminRoutes(), "admin-server")
+	if nil != err {
+		return err
+	}
+	instance.adminListener = listener
+	return nil
+}
+
+// startRPCServer serve routes on addr with a mux of its own.
+func startRPCServer(addr string, routes map[string]*rpcserver.RPCFunc, module string) (net.Listener, error) {
+	mux := http.NewServeMux()
+	logger := tmlog.NewTMLogger(tmlog.NewSyncWriter(os.Stdout)).With("module", module)
+	rpcserver.RegisterRPCFuncs(mux, routes, amino.NewCodec(), logger)
+	return rpcserver.StartHTTPServer(addr, mux, logger, rpcserver.Config{})
+}
+
+func (instance *Node) stopAdmin() error {
+	if nil == instance.adminListener {
+		return nil
+	}
+	return instance.adminListener.Close()
+}
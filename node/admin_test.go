@@ -0,0 +1,148 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
+	justitiaCommon "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/p2p"
+	p2pConf "github.com/DSiSc/p2p/config"
+	"github.com/DSiSc/repository"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdminSetLogLevel(t *testing.T) {
+	assert := assert.New(t)
+	origin := log.GetGlobalConfig()
+	defer log.SetGlobalConfig(origin)
+	log.SetGlobalConfig(&log.Config{
+		Enabled:        true,
+		Provider:       origin.Provider,
+		GlobalLogLevel: log.InfoLevel,
+		Appenders: map[string]*log.Appender{
+			config.ConsoleLogAppender: {Enabled: true, LogLevel: log.InfoLevel, Output: ioutil.Discard, Format: log.TextFmt},
+			config.FileLogAppender:    {Enabled: true, LogLevel: log.WarnLevel, Output: ioutil.Discard, Format: log.TextFmt},
+		},
+		OutputFlags: origin.OutputFlags,
+	})
+
+	levels, err := adminSetLogLevel("console", "debug")
+	assert.Nil(err)
+	assert.Equal(map[string]string{"console": "debug", "file": "warn"}, levels)
+	assert.Equal(log.DebugLevel, log.GetGlobalLogLevel())
+
+	levels, err = adminSetLogLevel("", "ERROR")
+	assert.Nil(err)
+	assert.Equal(map[string]string{"console": "error", "file": "error"}, levels)
+
+	_, err = adminSetLogLevel("console", "verbose")
+	assert.NotNil(err)
+	_, err = adminSetLogLevel("syslog", "info")
+	assert.NotNil(err)
+}
+
+func TestNode_AdminPeers(t *testing.T) {
+	assert := assert.New(t)
+	conf := mockNodeConfig()
+	conf.P2PConf = map[string]*p2pConf.P2PConfig{
+		config.BlockSyncerP2P: {
+			AddrBookFilePath: filepath.Join(t.TempDir(), "address.json"),
+			ListenAddress:    "tcp://127.0.0.1:0",
+			MaxConnOutBound:  1,
+			MaxConnInBound:   1,
+			DisableDNSSeed:   true,
+		},
+	}
+	service, err := New(
+		WithConfig(conf),
+		WithEventCenter(events.NewEvent()),
+		WithRepository(repository.InitRepository),
+		WithP2P(config.BlockP2P, newMockP2P()),
+		WithP2P(config.TxP2P, newMockP2P()),
+	)
+	assert.Nil(err)
+	node := service.(*Node)
+	assert.Nil(node.Start())
+	defer node.Stop(context.Background())
+	syncerP2P := node.blockSyncerP2P
+
+	_, err = node.adminPeers("consensus")
+	assert.NotNil(err)
+	_, err = node.adminAddPeer("blockSyncer", "127.0.0.1")
+	assert.NotNil(err)
+	_, err = node.adminAddPeer("tx", "tcp://127.0.0.1:46662")
+	assert.NotNil(err)
+
+	peers, err := node.adminAddPeer("blockSyncer", "tcp://127.0.0.1:46660")
+	assert.Nil(err)
+	assert.Equal([]string{"tcp://127.0.0.1:46660"}, peers.Persistent)
+	assert.NotEqual(syncerP2P, node.blockSyncerP2P)
+	_, ok := node.blockSyncerP2P.(*p2p.P2P)
+	assert.True(ok)
+	_, err = node.adminAddPeer("blockSyncer", "tcp://127.0.0.1:46660")
+	assert.NotNil(err)
+
+	peers, err = node.adminRemovePeer("blockSyncer", "tcp://127.0.0.1:46660")
+	assert.Nil(err)
+	assert.Empty(peers.Persistent)
+	_, err = node.adminRemovePeer("blockSyncer", "tcp://127.0.0.1:46660")
+	assert.NotNil(err)
+}
+
+func TestNode_AdminPeersNotRestartable(t *testing.T) {
+	assert := assert.New(t)
+	node := &Node{isRunning: true}
+	node.config.NodeType = justitiaCommon.ConsensusNode
+	node.config.ConsensusConf.PolicyName = consensusCommon.FbftPolicy
+	node.config.P2PConf = map[string]*p2pConf.P2PConfig{
+		config.BlockSyncerP2P: {},
+	}
+	_, err := node.adminAddPeer("blockSyncer", "tcp://127.0.0.1:46660")
+	assert.Equal(fmt.Errorf("restart is not supported by consensus policy fbft"), err)
+	assert.Empty(node.config.P2PConf[config.BlockSyncerP2P].PersistentPeers)
+}
+
+func TestNode_AdminInspect(t *testing.T) {
+	assert := assert.New(t)
+	node, _ := mockNode(t)
+
+	txs, err := node.adminTxpool()
+	assert.Nil(err)
+	assert.Empty(txs)
+
+	subscribers, err := node.adminSubscribers()
+	assert.Nil(err)
+	assert.NotEmpty(subscribers["blockCommitted"])
+	found := false
+	for _, name := range subscribers["blockCommitted"] {
+		found = found || strings.Contains(name, "eventsRegister")
+	}
+	assert.True(found)
+
+	_, err = node.adminPauseProduction()
+	assert.NotNil(err)
+	_, err = node.adminRoundStatus()
+	assert.NotNil(err)
+
+	values, err := node.adminConfig()
+	assert.Nil(err)
+	for _, value := range values {
+		if config.RepositoryPlugin == value.Key {
+			assert.Equal(repository.PLUGIN_MEMDB, value.Value)
+			assert.Equal(config.SourceDefault, value.Source)
+		}
+	}
+
+	node.systemContracts = map[string]types.Address{types.JustitiaMetaData: {19: 0xaa}}
+	contracts, err := node.adminSystemContracts()
+	assert.Nil(err)
+	assert.Equal(map[string]string{types.JustitiaMetaData: "0x00000000000000000000000000000000000000aa"}, contracts)
+}
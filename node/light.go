@@ -0,0 +1,108 @@
+package node
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/light"
+	"github.com/DSiSc/repository"
+	"github.com/DSiSc/validator/tools"
+	"net"
+)
+
+// buildLightServices create the subsystems of light node, which only syncs and verifies headers
+// through block syncer p2p, and fetches account state from full peers on demand.
+// Txpool, switches, propagators and consensus are not created.
+func (instance *Node) buildLightServices() error {
+	chain, err := repository.NewLatestStateRepository()
+	if nil != err {
+		log.Error("Get latest repository failed with error %v.", err)
+		return fmt.Errorf("get latest repository failed")
+	}
+	// the current block in repository, which is genesis block at least, is the trusted header to verify from.
+	// Its recorded hash is used, as genesis hash is not computed from its final header.
+	trusted := chain.GetCurrentBlock()
+	if nil == trusted {
+		log.Error("Get current block failed.")
+		return fmt.Errorf("get current block failed")
+	}
+	blockSyncerP2P, err := instance.newP2P(config.BlockSyncerP2P)
+	if err != nil {
+		log.Error("Init block syncer p2p failed.")
+		return fmt.Errorf("init block syncer p2p failed")
+	}
+	validators, err := lightValidators(instance.config)
+	if nil != err {
+		log.Error("Get validators of headers failed with error %v.", err)
+		return fmt.Errorf("get validators of headers failed: %v", err)
+	}
+	headerChain := light.NewHeaderChain(trusted.Header, common.HeaderHash(trusted), validators, instance.config.LightConf.HeaderCacheLimit)
+	instance.blockSyncerP2P = blockSyncerP2P
+	instance.headerChain = headerChain
+	instance.headerSyncer = light.NewHeaderSyncer(blockSyncerP2P, headerChain, instance.eventScope)
+	instance.stateClient = light.NewStateClient(instance.config.LightConf.FullPeers, headerChain)
+	return nil
+}
+
+// lightValidators return the validators of headers, which are the ones in light config or the participates in
+// genesis file, with the num of signatures consensus policy collects for a block.
+func lightValidators(conf config.NodeConfig) (*light.Validators, error) {
+	addresses := make([]types.Address, 0)
+	for _, validator := range conf.LightConf.Validators {
+		addresses = append(addresses, tools.HexToAddress(validator))
+	}
+	if 0 == len(addresses) {
+		participates, err := config.GetParticipatesFromGenesis()
+		if nil != err {
+			return nil, err
+		}
+		for _, participate := range participates {
+			addresses = append(addresses, participate.Address)
+		}
+	}
+	return light.NewValidators(addresses, consensusQuorum(conf.ConsensusConf.PolicyName, len(addresses))), nil
+}
+
+// consensusQuorum return the num of signatures a block gets from n participates under consensus policy.
+func consensusQuorum(policy string, n int) int {
+	switch policy {
+	case consensusCommon.SoloPolicy:
+		return int(consensusCommon.SoloConsensusNum)
+	case consensusCommon.FbftPolicy:
+		return n
+	default:
+		return n - (n-1)/3
+	}
+}
+
+// registerLightServices register the subsystems of light node followed by plugged services.
+func (instance *Node) registerLightServices() error {
+	builtin := []*serviceEntry{
+		{&serviceFunc{name: BlockSyncerP2PService, start: instance.blockSyncerP2P.Start, stop: func() error {
+			instance.blockSyncerP2P.Stop()
+			return nil
+		}}, nil},
+		{&serviceFunc{name: HeaderSyncerService, start: instance.headerSyncer.Start, stop: func() error {
+			instance.headerSyncer.Stop()
+			return nil
+		}}, []string{BlockSyncerP2PService}},
+	}
+	if common.BlankString != instance.config.ApiGatewayAddr {
+		builtin = append(builtin, &serviceEntry{&serviceFunc{name: RpcService, start: instance.startLightRpc, stop: instance.stopRpc},
+			[]string{HeaderSyncerService}})
+	}
+	return instance.setServices(builtin)
+}
+
+// startLightRpc serve the light apis instead of the full apigateway, which reads blocks from local repository.
+func (instance *Node) startLightRpc() error {
+	listener, err := startRPCServer(instance.config.ApiGatewayAddr, light.Routes(instance.headerChain, instance.stateClient), "light-rpc-server")
+	if nil != err {
+		return err
+	}
+	instance.rpcListeners = []net.Listener{listener}
+	return nil
+}
@@ -0,0 +1,141 @@
+package node
+
+import (
+	"expvar"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/monitor"
+	"github.com/DSiSc/justitia/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"sync"
+)
+
+// prometheus metrics of craft are only created by the first start of its prometheus server,
+// which could not be started again once stopped.
+var prometheusMetrics struct {
+	sync.Mutex
+	created bool
+}
+
+// monitorServers serve the monitor endpoints of node. Expvar and pprof servers of craft could not be stopped,
+// so they are served by node, as well as prometheus after the craft one stopped, so that each of them could
+// be moved to a new endpoint on reload.
+type monitorServers struct {
+	lock            sync.Mutex
+	craftPrometheus bool
+	prometheus      *http.Server
+	expvar          *http.Server
+	pprof           *http.Server
+}
+
+func newMonitorServers() *monitorServers {
+	return &monitorServers{}
+}
+
+func (servers *monitorServers) startPrometheus(conf monitor.PrometheusConfig) {
+	if !conf.PrometheusEnabled {
+		return
+	}
+	servers.lock.Lock()
+	defer servers.lock.Unlock()
+	prometheusMetrics.Lock()
+	first := !prometheusMetrics.created
+	prometheusMetrics.created = true
+	prometheusMetrics.Unlock()
+	if first {
+		monitor.StartPrometheusServer(conf)
+		servers.craftPrometheus = true
+		return
+	}
+	servers.prometheus = serveMonitor("prometheus", conf.PrometheusPort, promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer, promhttp.HandlerFor(
+			prometheus.DefaultGatherer,
+			promhttp.HandlerOpts{MaxRequestsInFlight: conf.PrometheusMaxConn},
+		),
+	))
+}
+
+func (servers *monitorServers) stopPrometheus() {
+	servers.lock.Lock()
+	defer servers.lock.Unlock()
+	if servers.craftPrometheus {
+		monitor.StopPrometheusServer()
+		servers.craftPrometheus = false
+	}
+	servers.prometheus = closeMonitor(servers.prometheus)
+}
+
+func (servers *monitorServers) startExpvar(conf monitor.ExpvarConfig) {
+	if !conf.ExpvarEnabled {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle(conf.ExpvarPath, expvar.Handler())
+	servers.lock.Lock()
+	defer servers.lock.Unlock()
+	servers.expvar = serveMonitor("expvar", conf.ExpvarPort, mux)
+}
+
+func (servers *monitorServers) stopExpvar() {
+	servers.lock.Lock()
+	defer servers.lock.Unlock()
+	servers.expvar = closeMonitor(servers.expvar)
+}
+
+func (servers *monitorServers) startPprof(conf monitor.PprofConfig) {
+	if !conf.PprofEnabled {
+		return
+	}
+	servers.lock.Lock()
+	defer servers.lock.Unlock()
+	// pprof handlers are registered to default serve mux.
+	servers.pprof = serveMonitor("pprof", conf.PprofPort, http.DefaultServeMux)
+}
+
+func (servers *monitorServers) stopPprof() {
+	servers.lock.Lock()
+	defer servers.lock.Unlock()
+	servers.pprof = closeMonitor(servers.pprof)
+}
+
+// start serve all monitor endpoints enabled in conf, failures are only logged as monitors are optional.
+func (servers *monitorServers) start(conf config.NodeConfig) {
+	servers.startPrometheus(conf.PrometheusConf)
+	servers.startExpvar(conf.ExpvarConf)
+	servers.startPprof(conf.PprofConf)
+}
+
+func (servers *monitorServers) stop() {
+	servers.stopPrometheus()
+	servers.stopExpvar()
+	servers.stopPprof()
+}
+
+func serveMonitor(name string, port string, handler http.Handler) *http.Server {
+	listener, err := net.Listen("tcp", ":"+port)
+	if nil != err {
+		log.Error("Start %s server failed with error %v.", name, err)
+		return nil
+	}
+	server := &http.Server{Handler: handler}
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			log.Error("%s server exit with error %v.", name, err)
+		}
+	}()
+	log.Info("Serve %s on port %s.", name, port)
+	return server
+}
+
+// closeMonitor close server if it is running, and return nil to replace it.
+func closeMonitor(server *http.Server) *http.Server {
+	if nil != server {
+		if err := server.Close(); nil != err {
+			log.Warn("Close monitor server failed with error %v.", err)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/DSiSc/apigateway"
 	rpc "github.com/DSiSc/apigateway/rpc/core"
@@ -12,40 +14,59 @@ import (
 	galaxyCommon "github.com/DSiSc/galaxy/common"
 	"github.com/DSiSc/galaxy/consensus"
 	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
-	"github.com/DSiSc/galaxy/consensus/policy/dbft"
-	"github.com/DSiSc/galaxy/consensus/policy/fbft"
 	"github.com/DSiSc/galaxy/participates"
 	"github.com/DSiSc/galaxy/role"
 	"github.com/DSiSc/gossipswitch"
 	"github.com/DSiSc/gossipswitch/port"
 	"github.com/DSiSc/justitia/common"
 	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/light"
 	"github.com/DSiSc/justitia/propagator"
 	"github.com/DSiSc/justitia/tools"
 	"github.com/DSiSc/justitia/tools/events"
 	"github.com/DSiSc/p2p"
 	"github.com/DSiSc/producer"
-	"github.com/DSiSc/repository"
 	"github.com/DSiSc/syncer"
 	"github.com/DSiSc/txpool"
 	"github.com/DSiSc/validator"
 	"github.com/DSiSc/validator/tools/account"
 	"net"
 	"os"
-	"strings"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
 type NodesService interface {
-	Start()
-	Stop() error
+	Start() error
+	Stop(ctx context.Context) error
 	Wait()
 	Restart() error
+	Reload(conf config.NodeConfig) (*ReloadResult, error)
+	Register(service Service, dependencies ...string) error
+	Health() map[string]error
 }
 
+// names of built-in services, which could be used as dependencies of plugged services.
+const (
+	TxSwitchService        = "tx switch"
+	BlockSwitchService     = "block switch"
+	BlockSyncerP2PService  = "block syncer p2p"
+	BlockSyncerService     = "block syncer"
+	BlockP2PService        = "block p2p"
+	BlockPropagatorService = "block propagator"
+	TxP2PService           = "tx p2p"
+	TxPropagatorService    = "tx propagator"
+	ConsensusService       = "consensus"
+	RpcService             = "rpc"
+	HeaderSyncerService    = "header syncer"
+)
+
 const msgChannelCacheLimit = 5
 
+// max time to wait for subsystems to stop on restart
+const restartStopTimeout = 30 * time.Second
+
 // node struct with all service
 type Node struct {
 	nodeWg          sync.WaitGroup
@@ -59,7 +80,9 @@ type Node struct {
 	blockSwitch     *gossipswitch.GossipSwitch
 	validator       *validator.Validator
 	rpcListeners    []net.Listener
+	adminListener   net.Listener
 	eventCenter     types.EventCenter
+	eventScope      *events.Scope
 	msgChannel      chan common.MsgType
 	serviceChannel  chan interface{}
 	blockSyncerP2P  p2p.P2PAPI
@@ -68,156 +91,301 @@ type Node struct {
 	blockPropagator *propagator.BlockPropagator
 	txP2P           p2p.P2PAPI
 	txPropagator    *propagator.TxPropagator
+	headerChain     *light.HeaderChain
+	headerSyncer    *light.HeaderSyncer
+	stateClient     *light.StateClient
+	pacer           *blockPacer
+	round           *roundMachine
+	roundPolicy     RoundPolicy
+	roundObservers  []RoundObserver
+	production      *productionGate
+	monitors        *monitorServers
+	lock            sync.Mutex
+	isRunning       bool
+	quitChan        chan struct{}
+	loopDone        chan struct{}
+	services        *ServiceRegistry
+	plugins         []*serviceEntry
+	injectedP2Ps    map[string]p2p.P2PAPI
+	closeRepository func() error
+	// addresses of system contracts deployed by genesis block, by contract name
+	systemContracts map[string]types.Address
 }
 
 func InitLog(args config.SysConfig, conf config.NodeConfig) {
+	applyLogArgs(args, conf)
+	if conf.Logger.Appenders[config.FileLogAppender].Enabled {
+		logfile, err := openLogFile(conf.Logger.Appenders[config.FileLogAppender].LogPath)
+		if err != nil {
+			panic(err)
+		}
+		conf.Logger.Appenders[config.FileLogAppender].Output = logfile
+	}
+
+	log.SetGlobalConfig(&conf.Logger)
+}
+
+// applyLogArgs override file log setting of conf with command line args.
+func applyLogArgs(args config.SysConfig, conf config.NodeConfig) {
 	var logPath = args.LogPath
 	if common.BlankString != logPath {
 		conf.Logger.Appenders[config.FileLogAppender].Enabled = true
-		conf.Logger.Appenders[config.FileLogAppender].LogPath = logPath
+		conf.Logger.Appenders[config.FileLogAppender].LogPath = config.ResolvePath(logPath, common.BlankString)
+		config.SetValueSource(conf, config.SourceFlag, config.LogFileEnabled, config.LogFilePath)
 	}
 	var logFormat = args.LogStyle
 	if common.BlankString != logFormat {
 		conf.Logger.Appenders[config.FileLogAppender].Enabled = true
 		conf.Logger.Appenders[config.FileLogAppender].Format = logFormat
+		config.SetValueSource(conf, config.SourceFlag, config.LogFileEnabled, config.LogFileFormat)
 	}
 	var logLevel = args.LogLevel
 	if common.InvalidInt != int(logLevel) {
 		conf.Logger.Appenders[config.FileLogAppender].Enabled = true
 		conf.Logger.Appenders[config.FileLogAppender].LogLevel = log.Level(uint8(logLevel))
+		config.SetValueSource(conf, config.SourceFlag, config.LogFileEnabled, config.LogFileLevel)
 	}
+}
 
-	if conf.Logger.Appenders[config.FileLogAppender].Enabled {
-		// initialize logfile
-		logPath = conf.Logger.Appenders[config.FileLogAppender].LogPath
-		tools.EnsureFolderExist(logPath[0:strings.LastIndex(logPath, "/")])
-		logfile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
-		if err != nil {
-			panic(err)
-		}
-		conf.Logger.Appenders[config.FileLogAppender].Output = logfile
-	}
-
-	log.SetGlobalConfig(&conf.Logger)
+func openLogFile(logPath string) (*os.File, error) {
+	tools.EnsureFolderExist(filepath.Dir(logPath))
+	return os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
 }
 
 func NewNode(args config.SysConfig) (NodesService, error) {
-	nodeConf := config.NewNodeConfig()
+	nodeConf, err := config.LoadNodeConfig()
+	if nil != err {
+		return nil, err
+	}
 	InitLog(args, nodeConf)
+	return New(WithConfig(nodeConf))
+}
+
+// New create a node with options, config is loaded from justitia.yaml, and a new event center,
+// repository and p2p instances are created from config unless they are specified by options.
+// Rpc is only served when config has an api gateway address, as it is bound to a package level switch channel.
+func New(opts ...Option) (NodesService, error) {
+	options := &options{
+		initRepository: defaultRepository,
+		p2ps:           make(map[string]p2p.P2PAPI),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if nil == options.config {
+		nodeConf, err := config.LoadNodeConfig()
+		if nil != err {
+			return nil, err
+		}
+		options.config = &nodeConf
+	}
+	if nil == options.eventCenter {
+		options.eventCenter = events.NewEvent()
+	}
+	nodeConf := *options.config
 	craftConfig.GlobalConfig.Store(craftConfig.HashAlgName, nodeConf.AlgorithmConf.HashAlgorithm)
-	eventsCenter := events.NewEvent()
-	pool := txpool.NewTxPool(nodeConf.TxPoolConf, eventsCenter)
+	err := options.initRepository(nodeConf.RepositoryConf, options.eventCenter)
+	if err != nil {
+		log.Error("Init block chain failed with error %v.", err)
+		return nil, fmt.Errorf("Repository init failed: %v", err)
+	}
+	systemContracts, err := options.systemContracts()
+	if nil != err {
+		return nil, err
+	}
+	node := &Node{
+		config:          nodeConf,
+		eventCenter:     options.eventCenter,
+		eventScope:      events.NewScope(options.eventCenter),
+		serviceChannel:  make(chan interface{}),
+		injectedP2Ps:    options.p2ps,
+		roundObservers:  options.roundObservers,
+		production:      newProductionGate(),
+		monitors:        newMonitorServers(),
+		systemContracts: systemContracts,
+	}
+	if !options.injectedRepository {
+		node.closeRepository = closeRepository
+	}
+	for name, address := range node.systemContracts {
+		log.Info("System contract %s is at %x.", name, address)
+	}
+	if err = node.buildServices(); nil != err {
+		return nil, err
+	}
+	if common.ConsensusNode == nodeConf.NodeType {
+		if err = node.buildConsensus(); nil != err {
+			return nil, err
+		}
+	}
+	if err = node.registerServices(); nil != err {
+		return nil, err
+	}
+	node.eventsRegister()
+	return node, nil
+}
+
+// build create all subsystems from node config and wire them up, which is used by restart.
+func (instance *Node) build() error {
+	if err := instance.buildServices(); nil != err {
+		return err
+	}
+	if common.ConsensusNode == instance.config.NodeType {
+		if err := instance.buildConsensus(); nil != err {
+			return err
+		}
+	}
+	if err := instance.registerServices(); nil != err {
+		return err
+	}
+	instance.eventsRegister()
+	return nil
+}
+
+// buildServices create txpool, switches, p2p, block syncer and propagators from node config.
+// It is called again on restart, as none of them could be started once stopped.
+func (instance *Node) buildServices() error {
+	if common.LightNode == instance.config.NodeType {
+		return instance.buildLightServices()
+	}
+	nodeConf := instance.config
+	eventsCenter := instance.eventScope
+	pool := newTxsLimiter(nodeConf.TxPoolConf, eventsCenter)
+	pacer := newBlockPacer(time.Duration(nodeConf.BlockInterval)*time.Millisecond, nodeConf.TxPoolConf.MaxTrsPerBlock, nodeConf.ConsensusConf.EnableEmptyBlock)
 	txSwitch, err := gossipswitch.NewGossipSwitchByType(gossipswitch.TxSwitch, eventsCenter, nodeConf.SwitchConf[config.TxSwitxh])
 	if err != nil {
 		log.Error("Init txSwitch failed.")
-		return nil, fmt.Errorf("txswitch init failed")
+		return fmt.Errorf("txswitch init failed")
+	}
+	if common.BlankString != nodeConf.ApiGatewayAddr {
+		rpc.SetSwCh(txSwitch.InPort(port.LocalInPortId).Channel())
 	}
-	swChIn := txSwitch.InPort(port.LocalInPortId).Channel()
-	rpc.SetSwCh(swChIn)
 	err = txSwitch.OutPort(port.LocalInPortId).BindToPort(func(msg interface{}) error {
-		return pool.AddTx(msg.(*types.Transaction))
+		if err := pool.AddTx(msg.(*types.Transaction)); nil != err {
+			return err
+		}
+		pacer.txAdded()
+		return nil
 	})
 	if err != nil {
 		log.Error("Register txpool failed.")
-		return nil, fmt.Errorf("registe txpool failed")
+		return fmt.Errorf("registe txpool failed")
 	}
 	blkSwitch, err := gossipswitch.NewGossipSwitchByType(gossipswitch.BlockSwitch, eventsCenter, nodeConf.SwitchConf[config.BlockSwitch])
 	if err != nil {
 		log.Error("Init block switch failed.")
-		return nil, fmt.Errorf("blkSwitch init failed")
+		return fmt.Errorf("blkSwitch init failed")
 	}
-	err = repository.InitRepository(nodeConf.RepositoryConf, eventsCenter)
-	if err != nil {
-		log.Error("Init block chain failed.")
-		return nil, fmt.Errorf("Repository init failed")
-	}
-	config.ImportGenesisBlock()
-	blockSyncerP2P, err := p2p.NewP2P(nodeConf.P2PConf[config.BlockSyncerP2P], eventsCenter)
+	blockSyncerP2P, err := instance.newP2P(config.BlockSyncerP2P)
 	if err != nil {
 		log.Error("Init block syncer p2p failed.")
-		return nil, fmt.Errorf("init block syncer p2p failed")
+		return fmt.Errorf("init block syncer p2p failed")
 	}
 	blockSyncer, err := syncer.NewBlockSyncer(blockSyncerP2P, blkSwitch.InPort(port.LocalInPortId).Channel(), eventsCenter)
 	if err != nil {
 		log.Error("Init block syncer failed.")
-		return nil, fmt.Errorf("init block syncer failed")
+		return fmt.Errorf("init block syncer failed")
 	}
-	blockP2P, err := p2p.NewP2P(nodeConf.P2PConf[config.BlockP2P], eventsCenter)
+	blockP2P, err := instance.newP2P(config.BlockP2P)
 	if err != nil {
 		log.Error("Init block p2p failed.")
-		return nil, fmt.Errorf("init block p2p failed")
+		return fmt.Errorf("init block p2p failed")
 	}
 	blockPropagator, err := propagator.NewBlockPropagator(blockP2P, blkSwitch.InPort(port.RemoteInPortId).Channel(), eventsCenter)
 	if err != nil {
 		log.Error("Init block propagator failed.")
-		return nil, fmt.Errorf("init block propagator failed")
+		return fmt.Errorf("init block propagator failed")
 	}
-	txP2P, err := p2p.NewP2P(nodeConf.P2PConf[config.TxP2P], eventsCenter)
+	blockPropagator.SetInboundQueue(nodeConf.PropagatorConf.BlockQueueSize, nodeConf.PropagatorConf.DropPolicy)
+	txP2P, err := instance.newP2P(config.TxP2P)
 	if err != nil {
 		log.Error("Init tx p2p failed.")
-		return nil, fmt.Errorf("init tx p2p failed")
+		return fmt.Errorf("init tx p2p failed")
 	}
 	txPropagator, err := propagator.NewTxPropagator(txP2P, txSwitch.InPort(port.RemoteInPortId).Channel(), eventsCenter)
 	if err != nil {
 		log.Error("Init tx propagator failed.")
-		return nil, fmt.Errorf("init tx propagator failed")
+		return fmt.Errorf("init tx propagator failed")
+	}
+	txPropagator.SetInboundQueue(nodeConf.PropagatorConf.TxQueueSize, nodeConf.PropagatorConf.DropPolicy)
+	instance.txpool = pool
+	instance.pacer = pacer
+	instance.txSwitch = txSwitch
+	instance.blockSwitch = blkSwitch
+	instance.msgChannel = make(chan common.MsgType, msgChannelCacheLimit)
+	instance.blockSyncerP2P = blockSyncerP2P
+	instance.blockSyncer = blockSyncer
+	instance.blockP2P = blockP2P
+	instance.blockPropagator = blockPropagator
+	instance.txP2P = txP2P
+	instance.txPropagator = txPropagator
+	// producer holds the txpool, so it will be recreated on next round.
+	instance.producer = nil
+	return nil
+}
+
+// newP2P return the injected p2p instance with name, or create one from config.
+func (instance *Node) newP2P(name string) (p2p.P2PAPI, error) {
+	if p2pAPI, ok := instance.injectedP2Ps[name]; ok {
+		return p2pAPI, nil
 	}
-	node := &Node{
-		config:          nodeConf,
-		txpool:          pool,
-		txSwitch:        txSwitch,
-		blockSwitch:     blkSwitch,
-		eventCenter:     eventsCenter,
-		msgChannel:      make(chan common.MsgType, msgChannelCacheLimit),
-		serviceChannel:  make(chan interface{}),
-		blockSyncerP2P:  blockSyncerP2P,
-		blockSyncer:     blockSyncer,
-		blockP2P:        blockP2P,
-		blockPropagator: blockPropagator,
-		txP2P:           txP2P,
-		txPropagator:    txPropagator,
+	// p2p saves address book without creating its directory.
+	if addrBook := instance.config.P2PConf[name].AddrBookFilePath; common.BlankString != addrBook {
+		tools.EnsureFolderExist(filepath.Dir(addrBook))
 	}
-	if common.ConsensusNode == nodeConf.NodeType {
-		galaxyConfig := galaxyCommon.GalaxyPluginConf{
-			BlockSwitch:     blkSwitch.InPort(port.LocalInPortId).Channel(),
-			ParticipateConf: nodeConf.ParticipatesConf,
-			RoleConf:        nodeConf.RoleConf,
-			ConsensusConf:   nodeConf.ConsensusConf,
-		}
-		galaxyPlugin, err := galaxy.NewGalaxyPlugin(galaxyConfig)
-		if err != nil {
-			log.Error("Init galaxy plugin failed.")
-			return node, fmt.Errorf("init galaxy plugin failed with error %v", err)
-		}
-		node.participates = galaxyPlugin.Participates
-		node.role = galaxyPlugin.Role
-		node.consensus = galaxyPlugin.Consensus
-		// get node info
-		participates, err := node.participates.GetParticipates()
-		if err != nil {
-			log.Error("get participates failed with %v.", err)
-			panic("get participates failed.")
-		}
-		exits := false
-		for _, participate := range participates {
-			if participate.Address == node.config.Account.Address {
-				node.config.Account.Extension.Url = participate.Extension.Url
-				node.config.Account.Extension.Id = participate.Extension.Id
-				exits = true
-				break
-			}
-		}
-		if !exits {
-			panic("node type is consensus, while not found it by contract called")
+	return p2p.NewP2P(instance.config.P2PConf[name], instance.eventScope)
+}
+
+// buildConsensus create galaxy plugin which commits blocks to current block switch.
+func (instance *Node) buildConsensus() error {
+	galaxyConfig := galaxyCommon.GalaxyPluginConf{
+		BlockSwitch:     instance.blockSwitch.InPort(port.LocalInPortId).Channel(),
+		ParticipateConf: galaxyParticipatesConf(instance.config.ParticipatesConf),
+		RoleConf:        instance.config.RoleConf,
+		ConsensusConf:   instance.config.ConsensusConf,
+	}
+	galaxyPlugin, err := galaxy.NewGalaxyPlugin(galaxyConfig)
+	if err != nil {
+		log.Error("Init galaxy plugin failed.")
+		return fmt.Errorf("init galaxy plugin failed with error %v", err)
+	}
+	instance.participates = galaxyPlugin.Participates
+	if config.GenesisParticipatesPolicy == instance.config.ParticipatesConf.PolicyName {
+		if instance.participates, err = newGenesisParticipates(); nil != err {
+			log.Error("Init genesis participates failed with error %v.", err)
+			return fmt.Errorf("init genesis participates failed with error %v", err)
 		}
-		_, master, err := node.role.RoleAssignments(participates)
-		if nil != err {
-			panic(fmt.Sprintf("Role assignments failed with err %v.", err))
+	}
+	instance.role = galaxyPlugin.Role
+	instance.consensus = galaxyPlugin.Consensus
+	// get node info
+	participates, err := instance.participates.GetParticipates()
+	if err != nil {
+		log.Error("get participates failed with %v.", err)
+		return fmt.Errorf("get participates failed with error %v", err)
+	}
+	exits := false
+	for _, participate := range participates {
+		if participate.Address == instance.config.Account.Address {
+			instance.config.Account.Extension.Url = participate.Extension.Url
+			instance.config.Account.Extension.Id = participate.Extension.Id
+			exits = true
+			break
 		}
-		node.consensus.Initialization(node.config.Account, master, participates, node.eventCenter, false)
 	}
-	node.eventsRegister()
-	return node, nil
+	if !exits {
+		log.Error("node type is consensus, while not found it by contract called.")
+		return fmt.Errorf("node %x not found in participates", instance.config.Account.Address)
+	}
+	_, master, err := instance.role.RoleAssignments(participates)
+	if nil != err {
+		log.Error("Role assignments failed with err %v.", err)
+		return fmt.Errorf("role assignments failed with error %v", err)
+	}
+	instance.consensus.Initialization(instance.config.Account, master, participates, instance.eventScope, false)
+	instance.roundPolicy = newRoundPolicy(instance)
+	instance.round = newRoundMachine(instance.roundObservers)
+	return nil
 }
 
 func (instance *Node) eventsRegister() {
@@ -226,37 +394,41 @@ func (instance *Node) eventsRegister() {
 			block := v.(*types.Block)
 			log.Debug("begin delete txs after block %d committed success.", block.Header.Height)
 			instance.txpool.DelTxs(block.Transactions)
+			instance.pacer.txRemoved(len(block.Transactions))
 		}
 	}
-	instance.eventCenter.Subscribe(types.EventBlockCommitted, txDelEventFunc)
-	instance.eventCenter.Subscribe(types.EventBlockWritten, txDelEventFunc)
+	// light node keeps no txpool.
+	if common.LightNode != instance.config.NodeType {
+		instance.eventScope.Subscribe(types.EventBlockCommitted, txDelEventFunc)
+		instance.eventScope.Subscribe(types.EventBlockWritten, txDelEventFunc)
+	}
 	if common.ConsensusNode == instance.config.NodeType {
-		instance.eventCenter.Subscribe(types.EventBlockCommitted, func(v interface{}) {
+		instance.eventScope.Subscribe(types.EventBlockCommitted, func(v interface{}) {
 			instance.sendMsgInternal(common.MsgBlockCommitSuccess)
 		})
-		instance.eventCenter.Subscribe(types.EventBlockVerifyFailed, func(v interface{}) {
+		instance.eventScope.Subscribe(types.EventBlockVerifyFailed, func(v interface{}) {
 			instance.sendMsgInternal(common.MsgBlockVerifyFailed)
 		})
-		instance.eventCenter.Subscribe(types.EventBlockCommitFailed, func(v interface{}) {
+		instance.eventScope.Subscribe(types.EventBlockCommitFailed, func(v interface{}) {
 			instance.sendMsgInternal(common.MsgBlockCommitFailed)
 		})
-		instance.eventCenter.Subscribe(types.EventConsensusFailed, func(v interface{}) {
+		instance.eventScope.Subscribe(types.EventConsensusFailed, func(v interface{}) {
 			instance.sendMsgInternal(common.MsgToConsensusFailed)
 		})
-		instance.eventCenter.Subscribe(types.EventMasterChange, func(v interface{}) {
+		instance.eventScope.Subscribe(types.EventMasterChange, func(v interface{}) {
 			instance.sendMsgInternal(common.MsgChangeMaster)
 		})
-		instance.eventCenter.Subscribe(types.EventOnline, func(v interface{}) {
+		instance.eventScope.Subscribe(types.EventOnline, func(v interface{}) {
 			instance.sendMsgInternal(common.MsgOnline)
 		})
-		instance.eventCenter.Subscribe(types.EventBlockWithoutTxs, func(v interface{}) {
+		instance.eventScope.Subscribe(types.EventBlockWithoutTxs, func(v interface{}) {
 			instance.sendMsgInternal(common.MsgBlockWithoutTx)
 		})
 	}
 }
 
 func (instance *Node) eventUnregister() {
-	instance.eventCenter.UnSubscribeAll()
+	instance.eventScope.UnSubscribeAll()
 }
 
 func (instance *Node) notify() {
@@ -274,10 +446,13 @@ func (instance *Node) sendMsgInternal(msgType common.MsgType) {
 func (instance *Node) blockFactory(master account.Account, participates []account.Account) {
 	monitor.JTMetrics.ConsensusPeerId.Set(float64(instance.config.Account.Extension.Id))
 	monitor.JTMetrics.ConsensusMasterId.Set(float64(master.Extension.Id))
-	instance.consensus.Initialization(instance.config.Account, master, participates, instance.eventCenter, false)
+	instance.consensus.Initialization(instance.config.Account, master, participates, instance.eventScope, false)
+	// the round result, whatever it is, comes from events.
+	defer instance.round.transition(RoundCommitting)
 	isMaster := master == instance.config.Account
 	if isMaster {
 		log.Info("Master this round.")
+		instance.round.transition(RoundProducing)
 		if nil == instance.producer {
 			instance.producer = producer.NewProducer(instance.txpool, instance.config.Account, instance.config.ProducerConf)
 		}
@@ -287,9 +462,12 @@ func (instance *Node) blockFactory(master account.Account, participates []accoun
 			instance.notify()
 			return
 		}
+		// transactions not packed have expired or could not be executed yet.
+		instance.pacer.resetPending(len(block.Transactions))
 		proposal := &consensusCommon.Proposal{
 			Block: block,
 		}
+		instance.round.transition(RoundProposing)
 		if err = instance.consensus.ToConsensus(proposal); err != nil {
 			log.Error("ToConsensus failed with err %v.", err)
 		} else {
@@ -301,45 +479,72 @@ func (instance *Node) blockFactory(master account.Account, participates []accoun
 	}
 }
 
+// NextRound run the round triggered by msgType. Round policy of the consensus decides whether to wait
+// for block interval before it, and who is the master of it.
 func (instance *Node) NextRound(msgType common.MsgType) {
-	switch instance.consensus.(type) {
-	case *dbft.DBFTPolicy:
-		if common.MsgChangeMaster == msgType {
-			consensusResult := instance.consensus.GetConsensusResult()
-			instance.blockFactory(consensusResult.Master, consensusResult.Participate)
-		} else {
-			instance.Round()
+	if common.MsgBlockCommitSuccess == msgType {
+		instance.pacer.observe(instance.round.elapsed())
+	}
+	if instance.roundPolicy.Wait(msgType) {
+		instance.round.transition(RoundWaiting)
+		if !instance.pacer.wait(instance.round.elapsed(), instance.quitChan) {
+			return
 		}
-	case *fbft.FBFTPolicy:
-		consensusResult := instance.consensus.GetConsensusResult()
-		log.Debug("get participate %v and master %v.",
-			consensusResult.Participate, consensusResult.Master.Extension.Id)
-		if common.MsgBlockCommitSuccess == msgType || common.MsgChangeMaster == msgType {
-			//TODO: increase time spent
-			time.Sleep(time.Duration(instance.config.BlockInterval) * time.Millisecond)
+	}
+	if instance.production.paused() {
+		instance.round.transition(RoundPaused)
+		if !instance.production.wait(instance.quitChan) {
+			return
 		}
-		instance.blockFactory(consensusResult.Master, consensusResult.Participate)
-	default:
-		instance.Round()
 	}
-}
-
-func (instance *Node) Round() {
-	log.Debug("start a new round.")
-	time.Sleep(time.Duration(instance.config.BlockInterval) * time.Millisecond)
-	participate, err := instance.participates.GetParticipates()
+	instance.round.transition(RoundElecting)
+	master, participates, err := instance.roundPolicy.Assign(msgType)
 	if err != nil {
-		log.Error("get participates failed with error %s.", err)
+		instance.round.transition(RoundCommitting)
 		instance.notify()
 		return
 	}
-	_, master, err := instance.role.RoleAssignments(participate)
-	if nil != err {
-		log.Error("Role assignments failed with err %v.", err)
-		instance.notify()
-		return
+	instance.blockFactory(master, participates)
+}
+
+// PauseProduction hold back new rounds of block production until ResumeProduction, the round in progress
+// is not interrupted. It is kept across restarts. With bft policies, other participates may change view
+// if this node is the master.
+func (instance *Node) PauseProduction() error {
+	if common.ConsensusNode != instance.config.NodeType {
+		return errors.New("block production is only run by consensus node")
+	}
+	log.Warn("Block production paused.")
+	instance.production.pause()
+	return nil
+}
+
+// ResumeProduction resume block production paused by PauseProduction.
+func (instance *Node) ResumeProduction() error {
+	if common.ConsensusNode != instance.config.NodeType {
+		return errors.New("block production is only run by consensus node")
+	}
+	log.Warn("Block production resumed.")
+	instance.production.resume()
+	return nil
+}
+
+// RoundStatus return the state of block production round, which is zero value on node without consensus.
+func (instance *Node) RoundStatus() RoundStatus {
+	if nil == instance.round {
+		return RoundStatus{}
+	}
+	round, state, since := instance.round.status()
+	delay, average := instance.pacer.stats()
+	return RoundStatus{
+		Round:   round,
+		State:   state,
+		Since:   since,
+		Delay:   delay,
+		Average: average,
+		Timeout: instance.pacer.timeout(),
+		Pending: instance.pacer.pendingTxs(),
 	}
-	instance.blockFactory(master, participate)
 }
 
 /*
@@ -362,16 +567,22 @@ func (instance *Node) OnlineWizard() {
 }
 */
 func (instance *Node) mainLoop() {
+	defer close(instance.loopDone)
+	defer instance.round.transition(RoundStopped)
 	instance.consensus.Online()
+	instance.round.transition(RoundCommitting)
 	for {
-		timer := time.NewTimer(time.Duration(instance.config.BlockInterval) * 2 * time.Millisecond)
+		timer := time.NewTimer(instance.pacer.timeout())
 		var msg common.MsgType
 		select {
 		case msg = <-instance.msgChannel:
-		case <-timer.C:
 			timer.Stop()
+		case <-timer.C:
 			msg = common.MsgWaitTimeOut
 			log.Info("wait for node to produce new block time out, will start a new round")
+		case <-instance.quitChan:
+			timer.Stop()
+			msg = common.MsgNodeServiceStopped
 		}
 		switch msg {
 		case common.MsgBlockCommitSuccess:
@@ -401,104 +612,272 @@ func (instance *Node) mainLoop() {
 		case common.MsgWaitTimeOut:
 			instance.NextRound(common.MsgWaitTimeOut)
 		case common.MsgNodeServiceStopped:
-			log.Warn("Stop node service.")
+			log.Warn("Exit node main loop, as node service stopped.")
+			return
 		}
 	}
 }
 
-func (instance *Node) stratRpc() {
-	var err error
-	if instance.rpcListeners, err = apigateway.StartRPC(instance.config.ApiGatewayAddr, instance.eventCenter); nil != err {
-		panic(fmt.Sprintf("Rpc start failed with %v.", err))
-	}
+// registerServices register built-in subsystems to a new service registry, followed by services
+// registered through Register, so that all of them could be started in dependency order.
+// Dependencies keep the shutdown order of rpc, propagators, p2p, block syncer, switches and consensus,
+// so that consensus is the last one to stop while others may still feed it.
+func (instance *Node) registerServices() error {
+	if common.LightNode == instance.config.NodeType {
+		return instance.registerLightServices()
+	}
+	builtin := make([]*serviceEntry, 0)
+	var switchDependencies []string
+	if common.ConsensusNode == instance.config.NodeType {
+		builtin = append(builtin, &serviceEntry{&serviceFunc{name: ConsensusService, start: instance.startMainLoop, stop: instance.stopMainLoop, health: instance.mainLoopHealth}, nil})
+		switchDependencies = []string{ConsensusService}
+	}
+	p2pDependencies := []string{BlockSyncerService}
+	propagatorDependencies := []string{BlockSyncerP2PService, BlockP2PService, TxP2PService}
+	builtin = append(builtin,
+		&serviceEntry{&serviceFunc{name: TxSwitchService, start: instance.txSwitch.Start, stop: instance.txSwitch.Stop, health: switchHealth(instance.txSwitch)}, switchDependencies},
+		&serviceEntry{&serviceFunc{name: BlockSwitchService, start: instance.blockSwitch.Start, stop: instance.blockSwitch.Stop, health: switchHealth(instance.blockSwitch)}, switchDependencies},
+		&serviceEntry{&serviceFunc{name: BlockSyncerService, start: instance.blockSyncer.Start, stop: func() error {
+			instance.blockSyncer.Stop()
+			return nil
+		}}, []string{BlockSwitchService, TxSwitchService}},
+		&serviceEntry{&serviceFunc{name: TxP2PService, start: instance.txP2P.Start, stop: func() error {
+			instance.txP2P.Stop()
+			return nil
+		}}, p2pDependencies},
+		&serviceEntry{&serviceFunc{name: BlockP2PService, start: instance.blockP2P.Start, stop: func() error {
+			instance.blockP2P.Stop()
+			return nil
+		}}, p2pDependencies},
+		&serviceEntry{&serviceFunc{name: BlockSyncerP2PService, start: instance.blockSyncerP2P.Start, stop: func() error {
+			instance.blockSyncerP2P.Stop()
+			return nil
+		}}, p2pDependencies},
+		&serviceEntry{&serviceFunc{name: TxPropagatorService, start: instance.txPropagator.Start, stop: func() error {
+			instance.txPropagator.Stop()
+			return nil
+		}}, propagatorDependencies},
+		&serviceEntry{&serviceFunc{name: BlockPropagatorService, start: instance.blockPropagator.Start, stop: func() error {
+			instance.blockPropagator.Stop()
+			return nil
+		}}, propagatorDependencies},
+	)
+	if common.BlankString != instance.config.ApiGatewayAddr {
+		builtin = append(builtin, &serviceEntry{&serviceFunc{name: RpcService, start: instance.startRpc, stop: instance.stopRpc},
+			[]string{BlockPropagatorService, TxPropagatorService}})
+	}
+	return instance.setServices(builtin)
 }
 
-func (instance *Node) startSwitch() {
-	if err := instance.txSwitch.Start(); nil != err {
-		panic(fmt.Sprintf("TxSwitch start failed with %v.", err))
-	}
-	if err := instance.blockSwitch.Start(); nil != err {
-		panic(fmt.Sprintf("BlockSwitch start failed with %v.", err))
+// setServices replace node services with a registry of builtin services and plugged services.
+func (instance *Node) setServices(builtin []*serviceEntry) error {
+	services := NewServiceRegistry()
+	for _, entry := range append(builtin, instance.plugins...) {
+		if err := services.Register(entry.service, entry.dependencies...); nil != err {
+			return err
+		}
 	}
+	instance.services = services
+	return nil
 }
 
-func (instance *Node) startBlockSyncer() {
-	if err := instance.blockSyncerP2P.Start(); nil != err {
-		panic(fmt.Sprintf("Start block syncer p2p failed with error %v.", err))
-	}
-	if err := instance.blockSyncer.Start(); nil != err {
-		panic(fmt.Sprintf("Start block syncer failed with error %v.", err))
+func switchHealth(sw *gossipswitch.GossipSwitch) func() error {
+	return func() error {
+		if !sw.IsRunning() {
+			return errors.New("switch is not running")
+		}
+		return nil
 	}
 }
 
-func (instance *Node) startBlockPropagator() {
-	if err := instance.blockP2P.Start(); nil != err {
-		panic(fmt.Sprintf("Start block p2p failed with error %v.", err))
+func (instance *Node) startRpc() error {
+	var err error
+	instance.rpcListeners, err = apigateway.StartRPC(instance.config.ApiGatewayAddr, instance.eventScope)
+	return err
+}
+
+func (instance *Node) startMainLoop() error {
+	instance.quitChan = make(chan struct{})
+	instance.loopDone = make(chan struct{})
+	go instance.consensus.Start()
+	go instance.mainLoop()
+	return nil
+}
+
+func (instance *Node) mainLoopHealth() error {
+	select {
+	case <-instance.loopDone:
+		return errors.New("node main loop exited")
+	default:
+		return nil
 	}
-	if err := instance.blockPropagator.Start(); nil != err {
-		panic(fmt.Sprintf("Start block propagator failed with error %v.", err))
+}
+
+// Register plug a service into node, which will be started after its dependencies,
+// and stopped before them. Names of built-in services could be used as dependencies.
+// Services are stopped and started again on restart, and must be registered before node start.
+func (instance *Node) Register(service Service, dependencies ...string) error {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	if instance.isRunning {
+		return fmt.Errorf("can not register service %s to running node", service.Name())
+	}
+	if err := instance.services.Register(service, dependencies...); nil != err {
+		return err
 	}
+	instance.plugins = append(instance.plugins, &serviceEntry{
+		service:      service,
+		dependencies: dependencies,
+	})
+	return nil
+}
+
+// Health return health state of all services of the node.
+func (instance *Node) Health() map[string]error {
+	return instance.services.Health()
 }
 
-func (instance *Node) startTxPropagator() {
-	if err := instance.txP2P.Start(); nil != err {
-		panic(fmt.Sprintf("Start tx p2p failed with error %v.", err))
+func (instance *Node) Start() error {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	if instance.isRunning {
+		return errors.New("node service is already running")
+	}
+	if err := instance.services.Start(); nil != err {
+		log.Error("Start node service failed with error %v.", err)
+		return err
 	}
-	if err := instance.txPropagator.Start(); nil != err {
-		panic(fmt.Sprintf("Start tx propagator failed with error %v.", err))
+	if err := instance.startAdmin(); nil != err {
+		log.Error("Start admin rpc failed with error %v.", err)
+		return errors.Join(err, instance.services.Stop(context.Background()))
 	}
+	instance.monitors.start(instance.config)
+	instance.isRunning = true
+	return nil
 }
 
-func (instance *Node) Start() {
-	instance.stratRpc()
-	instance.startSwitch()
-	instance.startBlockSyncer()
-	instance.startBlockPropagator()
-	instance.startTxPropagator()
-	monitor.StartPrometheusServer(instance.config.PrometheusConf)
-	monitor.StartExpvarServer(instance.config.ExpvarConf)
-	monitor.StartPprofServer(instance.config.PprofConf)
-	if instance.config.NodeType == common.ConsensusNode {
-		go instance.consensus.Start()
-		go instance.mainLoop()
+// stopStep run the stop function of a subsystem, and give up waiting for it once ctx is done.
+func stopStep(ctx context.Context, name string, stop func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- stop()
+	}()
+	select {
+	case err := <-done:
+		if nil != err {
+			log.Error("Stop %s failed with error %v.", name, err)
+			return fmt.Errorf("stop %s failed: %v", name, err)
+		}
+		log.Info("Stop %s success.", name)
+		return nil
+	case <-ctx.Done():
+		log.Error("Stop %s failed with error %v.", name, ctx.Err())
+		return fmt.Errorf("stop %s failed: %v", name, ctx.Err())
 	}
 }
 
-func (instance *Node) Stop() error {
-	log.Warn("Stop node service.")
-	close(instance.serviceChannel)
-	var err error
+func (instance *Node) stopRpc() error {
+	var errs []error
 	for _, listener := range instance.rpcListeners {
 		if err := listener.Close(); err != nil {
-			log.Error("Stop rpc listeners failed with error %v.", err)
-			continue
+			errs = append(errs, err)
 		}
 	}
-	instance.blockSyncerP2P.Stop()
-	instance.blockSyncer.Stop()
-	instance.blockP2P.Stop()
-	instance.blockPropagator.Stop()
-	instance.txP2P.Stop()
-	instance.txPropagator.Stop()
-	instance.blockSwitch.Stop()
-	instance.txSwitch.Stop()
+	return errors.Join(errs...)
+}
+
+func (instance *Node) stopMainLoop() error {
+	close(instance.quitChan)
+	<-instance.loopDone
+	instance.consensus.Halt()
+	return nil
+}
+
+// stopServices stop all services in reverse dependency order, and unsubscribe events subscribed by them
+// and node, so that they can be rebuilt from scratch.
+func (instance *Node) stopServices(ctx context.Context) error {
+	err := instance.services.Stop(ctx)
 	instance.eventUnregister()
-	if instance.config.NodeType == common.ConsensusNode {
-		instance.msgChannel <- common.MsgNodeServiceStopped
-		monitor.StopPrometheusServer()
-	}
 	return err
 }
 
+// Stop stop all subsystems in reverse dependency order, and return the errors of all failed steps.
+// Subsystems that have not stopped when ctx is done will be reported as failed.
+func (instance *Node) Stop(ctx context.Context) error {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	if !instance.isRunning {
+		log.Warn("node service is not running.")
+		return errors.New("node service is not running")
+	}
+	log.Warn("Stop node service.")
+	instance.isRunning = false
+	return instance.shutdown(ctx)
+}
+
+// shutdown stop admin rpc, all services, monitor servers and repository opened by node, and release Wait.
+// Services already stopped are skipped, so it could also clean up a node whose restart failed.
+func (instance *Node) shutdown(ctx context.Context) error {
+	errs := []error{stopStep(ctx, "admin rpc", instance.stopAdmin)}
+	errs = append(errs, instance.stopServices(ctx))
+	errs = append(errs, stopStep(ctx, "monitor servers", func() error {
+		instance.monitors.stop()
+		return nil
+	}))
+	if nil != instance.closeRepository {
+		errs = append(errs, stopStep(ctx, "repository", instance.closeRepository))
+	}
+	close(instance.serviceChannel)
+	return errors.Join(errs...)
+}
+
 func (instance *Node) Wait() {
 	<-instance.serviceChannel
 }
 
+// restartable check whether subsystems of the node could be rebuilt in process.
+func (instance *Node) restartable() error {
+	// bft, dbft and fbft policies keep listening on consensus port after halt,
+	// so a rebuilt one could never bind it again.
+	if common.ConsensusNode == instance.config.NodeType &&
+		consensusCommon.SoloPolicy != instance.config.ConsensusConf.PolicyName {
+		return fmt.Errorf("restart is not supported by consensus policy %s", instance.config.ConsensusConf.PolicyName)
+	}
+	return nil
+}
+
+// Restart stop all subsystems, rebuild them from node config and start them again.
+// Admin rpc, monitor servers and repository are kept during restart.
+// If any step fails, the node is shut down as by Stop, so that Wait returns.
 func (instance *Node) Restart() error {
-	if err := instance.Stop(); err != nil {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	if !instance.isRunning {
+		log.Warn("node service is not running.")
+		return errors.New("node service is not running")
+	}
+	if err := instance.restartable(); nil != err {
 		log.Error("restart service failed with err %v.", err)
 		return err
 	}
-	instance.Start()
+	log.Warn("Restart node service.")
+	ctx, cancel := context.WithTimeout(context.Background(), restartStopTimeout)
+	defer cancel()
+	instance.isRunning = false
+	err := instance.stopServices(ctx)
+	if nil == err {
+		err = instance.build()
+	}
+	if nil == err {
+		err = instance.services.Start()
+	}
+	if nil != err {
+		log.Error("restart service failed with err %v, will stop node service.", err)
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), restartStopTimeout)
+		defer shutdownCancel()
+		return errors.Join(err, instance.shutdown(shutdownCtx))
+	}
+	instance.isRunning = true
+	log.Info("Restart node service success.")
 	return nil
 }
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/DSiSc/apigateway"
 	"github.com/DSiSc/craft/log"
@@ -33,11 +35,35 @@ import (
 	"github.com/DSiSc/validator/tools/account"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
+// TestMain run tests in a temporary home with config and genesis files of the source tree.
+func TestMain(m *testing.M) {
+	home, err := ioutil.TempDir("", "justitia")
+	if nil != err {
+		panic(err)
+	}
+	os.MkdirAll(filepath.Join(home, config.ConfigDirName), 0755)
+	for _, file := range []string{config.ConfigPrefix + ".yaml", config.GenesisFileName} {
+		content, err := ioutil.ReadFile(filepath.Join("..", config.ConfigDirName, file))
+		if nil != err {
+			panic(err)
+		}
+		ioutil.WriteFile(filepath.Join(home, config.ConfigDirName, file), content, 0644)
+	}
+	os.Setenv(config.HomeEnv, home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
 var defaultConf = config.SysConfig{
 	LogLevel: log.InfoLevel,
 	LogPath:  "/tmp/justitia.log",
@@ -99,7 +125,7 @@ func TestNewNode(t *testing.T) {
 	service, err = NewNode(defaultConf)
 	assert.NotNil(err)
 	assert.Nil(service)
-	assert.Equal(err, fmt.Errorf("Repository init failed"))
+	assert.Equal(err, fmt.Errorf("Repository init failed: mock Repository error"))
 
 	monkey.Patch(repository.InitRepository, func(repositoryConfig.RepositoryConfig, types.EventCenter) error {
 		return nil
@@ -107,8 +133,8 @@ func TestNewNode(t *testing.T) {
 	monkey.Patch(p2p.NewP2P, func(*p2pConfig.P2PConfig, types.EventCenter) (*p2p.P2P, error) {
 		return nil, fmt.Errorf("new p2p failed")
 	})
-	monkey.Patch(config.ImportGenesisBlock, func() {
-		return
+	monkey.Patch(config.ImportGenesisBlock, func() error {
+		return nil
 	})
 	service, err = NewNode(defaultConf)
 	assert.NotNil(err)
@@ -129,23 +155,24 @@ func TestNewNode(t *testing.T) {
 	monkey.Patch(syncer.NewBlockSyncer, func(p2p.P2PAPI, chan<- interface{}, types.EventCenter) (*syncer.BlockSyncer, error) {
 		return nil, nil
 	})
+	blockPropagator, _ := propagator.NewBlockPropagator(nil, nil, nil)
 	monkey.Patch(propagator.NewBlockPropagator, func(p2p.P2PAPI, chan<- interface{}, types.EventCenter) (*propagator.BlockPropagator, error) {
-		return nil, nil
+		return blockPropagator, nil
 	})
 	monkey.Patch(galaxy.NewGalaxyPlugin, func(galaxyCommon.GalaxyPluginConf) (*galaxyCommon.GalaxyPlugin, error) {
 		return nil, fmt.Errorf("error of NewGalaxyPlugin")
 	})
 	service, err = NewNode(defaultConf)
 	assert.Equal(err, fmt.Errorf("init galaxy plugin failed with error error of NewGalaxyPlugin"))
-	assert.NotNil(service)
+	assert.Nil(service)
 
 	monkey.Patch(galaxy.NewGalaxyPlugin, func(galaxyCommon.GalaxyPluginConf) (*galaxyCommon.GalaxyPlugin, error) {
 		return nil, nil
 	})
-	nodeConf := config.NewNodeConfig()
-	monkey.Patch(config.NewNodeConfig, func() config.NodeConfig {
+	nodeConf, _ := config.NewNodeConfig()
+	monkey.Patch(config.NewNodeConfig, func() (config.NodeConfig, error) {
 		nodeConf.NodeType = justitiaCommon.FullNode
-		return nodeConf
+		return nodeConf, nil
 	})
 	service, err = NewNode(defaultConf)
 	nodeService := service.(*Node)
@@ -181,6 +208,9 @@ func TestNode_Start(t *testing.T) {
 	monkey.Patch(repository.InitRepository, func(repositoryConfig.RepositoryConfig, types.EventCenter) error {
 		return nil
 	})
+	monkey.Patch(config.ImportGenesisBlock, func() error {
+		return nil
+	})
 	monkey.Patch(syncer.NewBlockSyncer, func(p2p.P2PAPI, chan<- interface{}, types.EventCenter) (*syncer.BlockSyncer, error) {
 		return nil, nil
 	})
@@ -227,14 +257,14 @@ func TestNode_Start(t *testing.T) {
 		return
 	})
 	go func() {
-		service.Start()
+		assert.Nil(service.Start())
 		nodeService := service.(*Node)
 		assert.NotNil(nodeService.rpcListeners)
 		assert.Equal(0, len(nodeService.rpcListeners))
 		ch <- 1
 	}()
 	<-ch
-	service.Stop()
+	assert.Nil(service.Stop(context.Background()))
 	monkey.UnpatchInstanceMethod(reflect.TypeOf(c), "Start")
 	monkey.UnpatchInstanceMethod(reflect.TypeOf(p), "Start")
 	monkey.UnpatchInstanceMethod(reflect.TypeOf(p), "Stop")
@@ -248,25 +278,64 @@ func TestNode_Start(t *testing.T) {
 }
 
 func TestNode_Restart(t *testing.T) {
-	var nodeService *Node
-	var node *Node
-	monkey.PatchInstanceMethod(reflect.TypeOf(node), "Stop", func(_ *Node) error {
-		return fmt.Errorf("node stop error")
-	})
-	err := nodeService.Restart()
-	assert.Equal(t, err, fmt.Errorf("node stop error"))
-	monkey.UnpatchInstanceMethod(reflect.TypeOf(node), "Stop")
+	assert := assert.New(t)
+	node := &Node{}
+	assert.Equal(errors.New("node service is not running"), node.Restart())
+
+	node.isRunning = true
+	node.config.NodeType = justitiaCommon.ConsensusNode
+	node.config.ConsensusConf.PolicyName = consensusCommon.FbftPolicy
+	assert.Equal(fmt.Errorf("restart is not supported by consensus policy fbft"), node.Restart())
+	assert.True(node.isRunning)
+}
+
+func TestNode_Restartable(t *testing.T) {
+	assert := assert.New(t)
+	node := &Node{}
+	node.config.NodeType = justitiaCommon.FullNode
+	node.config.ConsensusConf.PolicyName = consensusCommon.DbftPolicy
+	assert.Nil(node.restartable())
 
-	monkey.PatchInstanceMethod(reflect.TypeOf(node), "Stop", func(*Node) error {
+	node.config.NodeType = justitiaCommon.ConsensusNode
+	assert.NotNil(node.restartable())
+	node.config.ConsensusConf.PolicyName = consensusCommon.SoloPolicy
+	assert.Nil(node.restartable())
+}
+
+func TestNode_AdminDisabled(t *testing.T) {
+	assert := assert.New(t)
+	node := &Node{}
+	assert.Nil(node.startAdmin())
+	assert.Nil(node.adminListener)
+	assert.Nil(node.stopAdmin())
+}
+
+func TestStopStep(t *testing.T) {
+	assert := assert.New(t)
+	err := stopStep(context.Background(), "mock", func() error {
 		return nil
 	})
-	monkey.PatchInstanceMethod(reflect.TypeOf(node), "Start", func(*Node) {
-		return
+	assert.Nil(err)
+
+	err = stopStep(context.Background(), "mock", func() error {
+		return errors.New("mock stop error")
 	})
-	err = nodeService.Restart()
-	assert.Nil(t, err)
-	monkey.UnpatchInstanceMethod(reflect.TypeOf(node), "Stop")
-	monkey.UnpatchInstanceMethod(reflect.TypeOf(node), "Start")
+	assert.Equal(fmt.Errorf("stop mock failed: mock stop error"), err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	block := make(chan struct{})
+	defer close(block)
+	err = stopStep(ctx, "mock", func() error {
+		<-block
+		return nil
+	})
+	assert.Equal(fmt.Errorf("stop mock failed: %v", context.DeadlineExceeded), err)
+}
+
+func TestNode_StopNotRunning(t *testing.T) {
+	node := &Node{}
+	assert.NotNil(t, node.Stop(context.Background()))
 }
 
 var mockAccount = account.Account{
@@ -325,8 +394,8 @@ func TestNode_Round(t *testing.T) {
 	monkey.Patch(repository.InitRepository, func(repositoryConfig.RepositoryConfig, types.EventCenter) error {
 		return nil
 	})
-	monkey.Patch(config.ImportGenesisBlock, func() {
-		return
+	monkey.Patch(config.ImportGenesisBlock, func() error {
+		return nil
 	})
 	monkey.Patch(compiler.SolidityCompile, func(string) string {
 		return "608060405234801561001057600080fd5b506040805190810160405280600d81526020017f48656c6c6f2c20776f72"
@@ -340,7 +409,7 @@ func TestNode_Round(t *testing.T) {
 	monkey.PatchInstanceMethod(reflect.TypeOf(r), "RoleAssignments", func(*galaxySolo.SoloPolicy, []account.Account) (map[account.Account]common.Roler, account.Account, error) {
 		return nil, account.Account{}, fmt.Errorf("assignments failed")
 	})
-	node.Round()
+	node.NextRound(justitiaCommon.MsgNull)
 
 	monkey.PatchInstanceMethod(reflect.TypeOf(r), "RoleAssignments", func(*galaxySolo.SoloPolicy, []account.Account) (map[account.Account]common.Roler, account.Account, error) {
 		role := make(map[account.Account]common.Roler)
@@ -348,7 +417,7 @@ func TestNode_Round(t *testing.T) {
 		return role, account.Account{}, nil
 	})
 	assert.Nil(node.validator)
-	node.Round()
+	node.NextRound(justitiaCommon.MsgNull)
 
 	monkey.PatchInstanceMethod(reflect.TypeOf(r), "RoleAssignments", func(*galaxySolo.SoloPolicy, []account.Account) (map[account.Account]common.Roler, account.Account, error) {
 		role := make(map[account.Account]common.Roler)
@@ -360,7 +429,7 @@ func TestNode_Round(t *testing.T) {
 		return &types.Block{}, fmt.Errorf("make block failed")
 	})
 	assert.Nil(node.producer)
-	node.Round()
+	node.NextRound(justitiaCommon.MsgNull)
 
 	monkey.PatchInstanceMethod(reflect.TypeOf(p), "MakeBlock", func(*producer.Producer) (*types.Block, error) {
 		return &types.Block{}, nil
@@ -369,7 +438,7 @@ func TestNode_Round(t *testing.T) {
 	monkey.PatchInstanceMethod(reflect.TypeOf(c), "ToConsensus", func(*solo.SoloPolicy, *consensusCommon.Proposal) error {
 		return fmt.Errorf("consensus failed")
 	})
-	node.Round()
+	node.NextRound(justitiaCommon.MsgNull)
 	monkey.UnpatchAll()
 }
 
@@ -406,6 +475,7 @@ func TestNode_NextRound(t *testing.T) {
 	assert.NotNil(bft)
 	node := service.(*Node)
 	node.consensus = bft
+	node.roundPolicy = newDbftRound(node)
 	monkey.PatchInstanceMethod(reflect.TypeOf(bft), "GetConsensusResult", func(*dbft.DBFTPolicy) consensusCommon.ConsensusResult {
 		return consensusCommon.ConsensusResult{
 			View:        uint64(1),
@@ -415,10 +485,7 @@ func TestNode_NextRound(t *testing.T) {
 	})
 	node.NextRound(justitiaCommon.MsgChangeMaster)
 
-	monkey.PatchInstanceMethod(reflect.TypeOf(node), "Round", func(*Node) {
-		return
-	})
-	node.NextRound(justitiaCommon.MsgBlockCommitSuccess)
+	assert.True(node.roundPolicy.Wait(justitiaCommon.MsgBlockCommitSuccess))
 
 	bft1, err := fbft.NewFBFTPolicy(timeout, nil, true, consensusConfig.SignatureVerifySwitch{})
 	bft1.Initialization(mockAccounts[0], mockAccounts[0], make([]account.Account, 0), nil, true)
@@ -430,6 +497,7 @@ func TestNode_NextRound(t *testing.T) {
 		}
 	})
 	node.consensus = bft1
+	node.roundPolicy = newFbftRound(node)
 	node.NextRound(justitiaCommon.MsgBlockCommitSuccess)
 	monkey.UnpatchAll()
 }
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/p2p"
+	"github.com/DSiSc/repository"
+	repositoryConfig "github.com/DSiSc/repository/config"
+)
+
+// RepositoryInitializer prepare the block chain repository for a node.
+type RepositoryInitializer func(conf repositoryConfig.RepositoryConfig, eventCenter types.EventCenter) error
+
+// Option configure the node created by New.
+type Option func(*options)
+
+type options struct {
+	config         *config.NodeConfig
+	eventCenter    types.EventCenter
+	initRepository RepositoryInitializer
+	// repository is injected by WithRepository, which is owned by the caller
+	injectedRepository bool
+	genesis            *config.GenesisBlock
+	p2ps               map[string]p2p.P2PAPI
+	roundObservers     []RoundObserver
+}
+
+// WithConfig use conf instead of loading it from justitia.yaml.
+func WithConfig(conf config.NodeConfig) Option {
+	return func(opts *options) {
+		opts.config = &conf
+	}
+}
+
+// WithEventCenter use eventCenter instead of creating a new one.
+func WithEventCenter(eventCenter types.EventCenter) Option {
+	return func(opts *options) {
+		opts.eventCenter = eventCenter
+	}
+}
+
+// WithRepository use initRepository to prepare block chain repository, instead of initializing it
+// from config and importing genesis block. Txpool, producer and rpc read the chain through
+// the package level repository, so the repository itself is still shared in one process.
+// The repository is left open when node stops, as it is owned by the caller, and genesis file is not read,
+// so system contracts are only known if genesis of the chain is given by WithGenesis.
+func WithRepository(initRepository RepositoryInitializer) Option {
+	return func(opts *options) {
+		opts.initRepository = initRepository
+		opts.injectedRepository = true
+	}
+}
+
+// WithGenesis take system contracts from genesis instead of generating it from genesis file,
+// which must be the genesis block of the chain in repository.
+func WithGenesis(genesis *config.GenesisBlock) Option {
+	return func(opts *options) {
+		opts.genesis = genesis
+	}
+}
+
+// WithP2P use p2pAPI as the p2p instance with name, which is one of config.BlockSyncerP2P,
+// config.BlockP2P and config.TxP2P. Injected instances are reused on restart.
+func WithP2P(name string, p2pAPI p2p.P2PAPI) Option {
+	return func(opts *options) {
+		opts.p2ps[name] = p2pAPI
+	}
+}
+
+// WithRoundObserver notify observer of every round transition of consensus node.
+func WithRoundObserver(observer RoundObserver) Option {
+	return func(opts *options) {
+		opts.roundObservers = append(opts.roundObservers, observer)
+	}
+}
+
+// defaultRepository initialize repository from config, and import genesis block into it.
+func defaultRepository(conf repositoryConfig.RepositoryConfig, eventCenter types.EventCenter) error {
+	if err := repository.InitRepository(conf, eventCenter); nil != err {
+		return err
+	}
+	return config.ImportGenesisBlock()
+}
+
+// systemContracts return the addresses of system contracts deployed by genesis of the chain in repository.
+func (opts *options) systemContracts() (map[string]types.Address, error) {
+	genesis := opts.genesis
+	if nil == genesis {
+		if opts.injectedRepository {
+			log.Warn("System contracts are unknown, as repository is injected without genesis.")
+			return make(map[string]types.Address), nil
+		}
+		var err error
+		if genesis, err = config.GenerateGenesisBlock(); nil != err {
+			log.Error("Generate genesis block failed with error %v.", err)
+			return nil, err
+		}
+		return genesis.SystemContracts(), nil
+	}
+	chain, err := repository.NewLatestStateRepository()
+	if nil != err {
+		return nil, err
+	}
+	// an empty chain has no genesis block to check against
+	if block, err := chain.GetBlockByHeight(0); nil == err {
+		if hash, blockHash := common.HeaderHash(genesis.Block), common.HeaderHash(block); hash != blockHash {
+			return nil, fmt.Errorf("genesis hash %x differs from %x of the chain in repository", hash, blockHash)
+		}
+	}
+	return genesis.SystemContracts(), nil
+}
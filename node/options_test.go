@@ -0,0 +1,250 @@
+package node
+
+import (
+	"context"
+	"github.com/DSiSc/craft/types"
+	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
+	swConfig "github.com/DSiSc/gossipswitch/config"
+	justitiaCommon "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/light"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/p2p"
+	"github.com/DSiSc/p2p/common"
+	p2pConfig "github.com/DSiSc/p2p/config"
+	"github.com/DSiSc/p2p/message"
+	"github.com/DSiSc/repository"
+	repositoryConfig "github.com/DSiSc/repository/config"
+	"github.com/DSiSc/txpool"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockP2P struct {
+	msgChan chan *p2p.InternalMsg
+	started bool
+}
+
+func newMockP2P() *mockP2P {
+	return &mockP2P{
+		msgChan: make(chan *p2p.InternalMsg),
+	}
+}
+
+func (m *mockP2P) Start() error {
+	m.started = true
+	return nil
+}
+
+func (m *mockP2P) Stop() {
+	m.started = false
+}
+
+func (m *mockP2P) BroadCast(msg message.Message) {}
+
+func (m *mockP2P) SendMsg(peerAddr *common.NetAddress, msg message.Message) error {
+	return nil
+}
+
+func (m *mockP2P) Gather(peerFilter p2p.PeerFilter, reqMsg message.Message) error {
+	return nil
+}
+
+func (m *mockP2P) MessageChan() <-chan *p2p.InternalMsg {
+	return m.msgChan
+}
+
+func mockNodeConfig() config.NodeConfig {
+	return config.NodeConfig{
+		NodeType:   justitiaCommon.FullNode,
+		TxPoolConf: txpool.DefaultTxPoolConfig,
+		AlgorithmConf: config.AlgorithmConfig{
+			HashAlgorithm: "SHA256",
+		},
+		RepositoryConf: repositoryConfig.RepositoryConfig{
+			PluginName: repository.PLUGIN_MEMDB,
+		},
+		SwitchConf: map[string]*swConfig.SwitchConfig{
+			config.TxSwitxh:    {},
+			config.BlockSwitch: {},
+		},
+	}
+}
+
+func mockNode(t *testing.T, extra ...Option) (*Node, map[string]*mockP2P) {
+	p2ps := map[string]*mockP2P{
+		config.BlockSyncerP2P: newMockP2P(),
+		config.BlockP2P:       newMockP2P(),
+		config.TxP2P:          newMockP2P(),
+	}
+	opts := []Option{
+		WithConfig(mockNodeConfig()),
+		WithEventCenter(events.NewEvent()),
+		WithRepository(repository.InitRepository),
+	}
+	for name, p := range p2ps {
+		opts = append(opts, WithP2P(name, p))
+	}
+	service, err := New(append(opts, extra...)...)
+	assert.Nil(t, err)
+	return service.(*Node), p2ps
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+	node1, p2ps1 := mockNode(t)
+	node2, p2ps2 := mockNode(t)
+	assert.NotEqual(node1.eventCenter, node2.eventCenter)
+	assert.Nil(node1.services.Service(RpcService))
+
+	var events []string
+	assert.Nil(node1.Register(mockService("indexer", &events, nil), BlockSwitchService))
+	assert.Nil(node1.Start())
+	assert.Nil(node2.Start())
+	assert.True(p2ps1[config.BlockP2P].started)
+	assert.True(p2ps2[config.TxP2P].started)
+	assert.Equal([]string{"start indexer"}, events)
+	for name, err := range node1.Health() {
+		assert.Nil(err, name)
+	}
+	assert.NotNil(node1.Register(mockService("exporter", &events, nil)))
+
+	assert.Nil(node1.Stop(context.Background()))
+	assert.Nil(node2.Stop(context.Background()))
+	assert.False(p2ps1[config.BlockSyncerP2P].started)
+	assert.False(p2ps2[config.BlockSyncerP2P].started)
+	assert.Equal([]string{"start indexer", "stop indexer"}, events)
+}
+
+func TestNew_SharedEventCenter(t *testing.T) {
+	assert := assert.New(t)
+	center := events.NewEvent()
+	shared := center.Subscribe(types.EventBlockCommitted, func(interface{}) {})
+	node, _ := mockNode(t, WithEventCenter(center))
+	assert.Nil(node.Start())
+	assert.True(len(center.(*events.Event).Subscribers[types.EventBlockCommitted]) > 1)
+	assert.Nil(node.Restart())
+	assert.Nil(node.Stop(context.Background()))
+	// subscribers of node and its subsystems are removed, while the others are kept
+	assert.Equal(1, len(center.(*events.Event).Subscribers[types.EventBlockCommitted]))
+	assert.Contains(center.(*events.Event).Subscribers[types.EventBlockCommitted], shared)
+}
+
+func TestNew_Genesis(t *testing.T) {
+	assert := assert.New(t)
+	genesis, err := config.GenerateGenesisBlock()
+	assert.Nil(err)
+	assert.NotEmpty(genesis.SystemContracts())
+	node, _ := mockNode(t)
+	assert.Empty(node.systemContracts)
+	node, _ = mockNode(t, WithGenesis(genesis))
+	assert.Equal(genesis.SystemContracts(), node.systemContracts)
+
+	_, err = New(
+		WithConfig(mockNodeConfig()),
+		WithRepository(func(conf repositoryConfig.RepositoryConfig, eventCenter types.EventCenter) error {
+			if err := repository.InitRepository(conf, eventCenter); nil != err {
+				return err
+			}
+			chain, err := repository.NewLatestStateRepository()
+			if nil != err {
+				return err
+			}
+			block := &types.Block{Header: &types.Header{ChainID: 1}}
+			block.HeaderHash = light.HeaderHash(block.Header)
+			return chain.WriteBlock(block)
+		}),
+		WithGenesis(genesis),
+	)
+	assert.Contains(err.Error(), "differs from")
+}
+
+func TestNode_RestartInjected(t *testing.T) {
+	assert := assert.New(t)
+	node, p2ps := mockNode(t)
+	var events []string
+	assert.Nil(node.Register(mockService("indexer", &events, nil)))
+	assert.Nil(node.Start())
+	blockSwitch := node.blockSwitch
+	assert.Nil(node.Restart())
+	assert.NotEqual(blockSwitch, node.blockSwitch)
+	assert.True(p2ps[config.TxP2P].started)
+	assert.True(node.blockSwitch.IsRunning())
+	assert.Equal([]string{"start indexer", "stop indexer", "start indexer"}, events)
+	assert.Nil(node.Stop(context.Background()))
+}
+
+func TestNode_RestartBuildFailed(t *testing.T) {
+	assert := assert.New(t)
+	node, p2ps := mockNode(t)
+	var events []string
+	assert.Nil(node.Register(mockService("indexer", &events, nil)))
+	assert.Nil(node.Start())
+	// tx p2p is created from config on rebuild, which has an invalid listen address
+	delete(node.injectedP2Ps, config.TxP2P)
+	node.config.P2PConf = map[string]*p2pConfig.P2PConfig{config.TxP2P: {ListenAddress: "invalid"}}
+	assert.NotNil(node.Restart())
+	assert.False(p2ps[config.BlockP2P].started)
+	assert.Equal([]string{"start indexer", "stop indexer"}, events)
+
+	waited := make(chan struct{})
+	go func() {
+		node.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		assert.Fail("node is not stopped after restart failed")
+	}
+	assert.NotNil(node.Stop(context.Background()))
+}
+
+func TestNew_LightNode(t *testing.T) {
+	assert := assert.New(t)
+	syncerP2P := newMockP2P()
+	conf := mockNodeConfig()
+	conf.NodeType = justitiaCommon.LightNode
+	conf.LightConf = config.LightConfig{
+		HeaderCacheLimit: config.DefaultLightHeaderCacheLimit,
+		Validators:       []string{"333c3310824b7c685133f2bedb2ca4b8b4df633d"},
+	}
+	service, err := New(
+		WithConfig(conf),
+		WithEventCenter(events.NewEvent()),
+		WithRepository(func(conf repositoryConfig.RepositoryConfig, eventCenter types.EventCenter) error {
+			if err := repository.InitRepository(conf, eventCenter); nil != err {
+				return err
+			}
+			chain, err := repository.NewLatestStateRepository()
+			if nil != err {
+				return err
+			}
+			genesis := &types.Block{Header: &types.Header{ChainID: 1}}
+			genesis.HeaderHash = light.HeaderHash(genesis.Header)
+			return chain.WriteBlock(genesis)
+		}),
+		WithP2P(config.BlockSyncerP2P, syncerP2P),
+	)
+	assert.Nil(err)
+	node := service.(*Node)
+	assert.Nil(node.txpool)
+	assert.Nil(node.services.Service(TxSwitchService))
+	assert.NotNil(node.services.Service(HeaderSyncerService))
+
+	assert.Nil(node.Start())
+	assert.True(syncerP2P.started)
+	assert.Nil(node.Restart())
+	assert.True(syncerP2P.started)
+	assert.Nil(node.Stop(context.Background()))
+	assert.False(syncerP2P.started)
+}
+
+func TestConsensusQuorum(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(1, consensusQuorum(consensusCommon.SoloPolicy, 1))
+	assert.Equal(4, consensusQuorum(consensusCommon.FbftPolicy, 4))
+	assert.Equal(3, consensusQuorum(consensusCommon.DbftPolicy, 4))
+	assert.Equal(5, consensusQuorum(consensusCommon.BftPolicy, 7))
+}
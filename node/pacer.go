@@ -0,0 +1,171 @@
+package node
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// a round is given up if no result arrived within this factor of average round time.
+	roundTimeoutFactor = 3
+	// lower bound of round timeout, in case of tiny block interval.
+	minRoundTimeout = time.Second
+	// weight of newest sample in average round time.
+	roundAverageWeight = 0.25
+)
+
+// blockPacer decide how long to wait before next round. Waiting time is the block interval minus time
+// spent by last round, shortened as txpool fills up, so that full blocks are produced without delay.
+// An idle wait, with empty txpool while empty block disabled, ends early once transactions arrive.
+type blockPacer struct {
+	lock       sync.Mutex
+	interval   time.Duration
+	blockSize  uint64
+	emptyBlock bool
+	average    time.Duration
+	delay      time.Duration
+	pending    int64
+	arrival    chan struct{}
+}
+
+func newBlockPacer(interval time.Duration, blockSize uint64, emptyBlock bool) *blockPacer {
+	return &blockPacer{
+		interval:   interval,
+		blockSize:  blockSize,
+		emptyBlock: emptyBlock,
+		arrival:    make(chan struct{}, 1),
+	}
+}
+
+// txAdded record a transaction added to txpool.
+func (pacer *blockPacer) txAdded() {
+	atomic.AddInt64(&pacer.pending, 1)
+	select {
+	case pacer.arrival <- struct{}{}:
+	default:
+	}
+}
+
+// txRemoved record transactions removed from txpool as block committed.
+func (pacer *blockPacer) txRemoved(count int) {
+	if atomic.AddInt64(&pacer.pending, -int64(count)) < 0 {
+		atomic.StoreInt64(&pacer.pending, 0)
+	}
+}
+
+// resetPending correct the number of pending transactions, as expired transactions are
+// dropped by txpool silently.
+func (pacer *blockPacer) resetPending(count int) {
+	atomic.StoreInt64(&pacer.pending, int64(count))
+}
+
+func (pacer *blockPacer) pendingTxs() int64 {
+	return atomic.LoadInt64(&pacer.pending)
+}
+
+// setLimits change block interval and block size, which take effect from next wait.
+func (pacer *blockPacer) setLimits(interval time.Duration, blockSize uint64) {
+	pacer.lock.Lock()
+	defer pacer.lock.Unlock()
+	pacer.interval = interval
+	pacer.blockSize = blockSize
+}
+
+func (pacer *blockPacer) limits() (time.Duration, uint64) {
+	pacer.lock.Lock()
+	defer pacer.lock.Unlock()
+	return pacer.interval, pacer.blockSize
+}
+
+// occupancy return pending transactions in proportion to block size.
+func (pacer *blockPacer) occupancy(blockSize uint64) float64 {
+	if 0 == blockSize {
+		return 0
+	}
+	return float64(pacer.pendingTxs()) / float64(blockSize)
+}
+
+// observe record the time spent by a round from its beginning to block committed.
+func (pacer *blockPacer) observe(spent time.Duration) {
+	pacer.lock.Lock()
+	defer pacer.lock.Unlock()
+	if 0 == pacer.average {
+		pacer.average = spent
+		return
+	}
+	pacer.average += time.Duration(roundAverageWeight * float64(spent-pacer.average))
+}
+
+// nextDelay return how long to wait before next round, as spent has passed since last round began.
+func (pacer *blockPacer) nextDelay(spent time.Duration) time.Duration {
+	interval, blockSize := pacer.limits()
+	remain := interval - spent
+	occupancy := pacer.occupancy(blockSize)
+	if remain <= 0 || occupancy >= 1 {
+		return 0
+	}
+	return time.Duration(float64(remain) * (1 - occupancy))
+}
+
+func (pacer *blockPacer) idle() bool {
+	return !pacer.emptyBlock && 0 == pacer.pendingTxs()
+}
+
+// wait block until next round should begin, and return false if quit closed meanwhile.
+func (pacer *blockPacer) wait(spent time.Duration, quit <-chan struct{}) bool {
+	delay := pacer.nextDelay(spent)
+	pacer.lock.Lock()
+	pacer.delay = delay
+	pacer.lock.Unlock()
+	// drop arrivals before waiting, they have been counted in delay.
+	select {
+	case <-pacer.arrival:
+	default:
+	}
+	idle := pacer.idle()
+	interval, _ := pacer.limits()
+	deadline := time.Now().Add(delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case <-pacer.arrival:
+			if !idle {
+				continue
+			}
+			// leave a short time for more transactions to arrive, instead of producing a block for each.
+			idle = false
+			remain := time.Until(deadline)
+			if batch := interval / 4; batch < remain {
+				timer.Stop()
+				timer.Reset(batch)
+			}
+		case <-quit:
+			return false
+		}
+	}
+}
+
+// timeout return how long to wait for the result of a round before starting a new one.
+func (pacer *blockPacer) timeout() time.Duration {
+	pacer.lock.Lock()
+	defer pacer.lock.Unlock()
+	timeout := roundTimeoutFactor * pacer.average
+	if timeout < pacer.interval {
+		timeout = pacer.interval
+	}
+	if timeout < minRoundTimeout {
+		timeout = minRoundTimeout
+	}
+	return timeout
+}
+
+// stats return the last delay and average round time.
+func (pacer *blockPacer) stats() (time.Duration, time.Duration) {
+	pacer.lock.Lock()
+	defer pacer.lock.Unlock()
+	return pacer.delay, pacer.average
+}
@@ -0,0 +1,80 @@
+package node
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBlockPacer_NextDelay(t *testing.T) {
+	assert := assert.New(t)
+	pacer := newBlockPacer(time.Second, 10, true)
+	assert.Equal(time.Second, pacer.nextDelay(0))
+	assert.Equal(600*time.Millisecond, pacer.nextDelay(400*time.Millisecond))
+	// slow round leaves no time to wait
+	assert.Equal(time.Duration(0), pacer.nextDelay(2*time.Second))
+
+	for i := 0; i < 5; i++ {
+		pacer.txAdded()
+	}
+	assert.Equal(500*time.Millisecond, pacer.nextDelay(0))
+	pacer.txRemoved(2)
+	assert.Equal(int64(3), pacer.pendingTxs())
+	pacer.resetPending(10)
+	assert.Equal(time.Duration(0), pacer.nextDelay(0))
+	pacer.txRemoved(20)
+	assert.Equal(int64(0), pacer.pendingTxs())
+}
+
+func TestBlockPacer_Timeout(t *testing.T) {
+	assert := assert.New(t)
+	pacer := newBlockPacer(2*time.Second, 10, true)
+	assert.Equal(2*time.Second, pacer.timeout())
+	pacer.observe(time.Second)
+	assert.Equal(3*time.Second, pacer.timeout())
+	pacer.observe(5 * time.Second)
+	_, average := pacer.stats()
+	assert.Equal(2*time.Second, average)
+
+	pacer = newBlockPacer(0, 10, true)
+	assert.Equal(minRoundTimeout, pacer.timeout())
+}
+
+func TestBlockPacer_Wait(t *testing.T) {
+	assert := assert.New(t)
+	quit := make(chan struct{})
+	pacer := newBlockPacer(time.Hour, 10, false)
+	done := make(chan bool)
+	go func() {
+		done <- pacer.wait(time.Hour-time.Minute, quit)
+	}()
+	close(quit)
+	assert.False(<-done)
+
+	// idle wait ends shortly after transactions arrive
+	pacer = newBlockPacer(80*time.Millisecond, 10, false)
+	start := time.Now()
+	go func() {
+		done <- pacer.wait(0, make(chan struct{}))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	pacer.txAdded()
+	assert.True(<-done)
+	assert.True(time.Since(start) < 70*time.Millisecond)
+	delay, _ := pacer.stats()
+	assert.Equal(80*time.Millisecond, delay)
+}
+
+func TestBlockPacer_SetLimits(t *testing.T) {
+	assert := assert.New(t)
+	pacer := newBlockPacer(time.Second, 10, true)
+	for i := 0; i < 5; i++ {
+		pacer.txAdded()
+	}
+	assert.Equal(500*time.Millisecond, pacer.nextDelay(0))
+	pacer.setLimits(2*time.Second, 5)
+	assert.Equal(time.Duration(0), pacer.nextDelay(0))
+	pacer.setLimits(2*time.Second, 20)
+	assert.Equal(1500*time.Millisecond, pacer.nextDelay(0))
+	assert.Equal(2*time.Second, pacer.timeout())
+}
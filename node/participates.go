@@ -0,0 +1,41 @@
+package node
+
+import (
+	"github.com/DSiSc/galaxy/participates"
+	participatesCommon "github.com/DSiSc/galaxy/participates/common"
+	participatesConfig "github.com/DSiSc/galaxy/participates/config"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/validator/tools/account"
+)
+
+// genesisParticipates serve the participates listed in genesis file, so that a network could be set up
+// without deploying the voting contract.
+type genesisParticipates struct {
+	participates []account.Account
+}
+
+func newGenesisParticipates() (participates.Participates, error) {
+	participates, err := config.GetParticipatesFromGenesis()
+	if nil != err {
+		return nil, err
+	}
+	return &genesisParticipates{participates: participates}, nil
+}
+
+func (instance *genesisParticipates) PolicyName() string {
+	return config.GenesisParticipatesPolicy
+}
+
+func (instance *genesisParticipates) GetParticipates() ([]account.Account, error) {
+	participates := make([]account.Account, len(instance.participates))
+	copy(participates, instance.participates)
+	return participates, nil
+}
+
+// galaxyParticipatesConf return the participates config passed to galaxy, which does not know genesis policy.
+func galaxyParticipatesConf(conf participatesConfig.ParticipateConfig) participatesConfig.ParticipateConfig {
+	if config.GenesisParticipatesPolicy == conf.PolicyName {
+		conf.PolicyName = participatesCommon.SoloPolicy
+	}
+	return conf
+}
@@ -0,0 +1,46 @@
+package node
+
+import (
+	participatesConfig "github.com/DSiSc/galaxy/participates/config"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/justitia/tools"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenesisParticipates(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	config.SetHome(dir)
+	defer config.SetHome("")
+
+	_, err = newGenesisParticipates()
+	assert.NotNil(err)
+
+	genesis := `{"Block": {"Header": {"chainId": 0}}, "participates": [
+		{"addr": "0x333c3310824b7c685133f2bedb2ca4b8b4df633d", "id": 0, "url": "127.0.0.1:30005"},
+		{"addr": "0x343c3310824b7c685133f2bedb2ca4b8b4df633d", "id": 1, "url": "127.0.0.1:30015"}]}`
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, config.GenesisFileName), []byte(genesis), 0644))
+	participates, err := newGenesisParticipates()
+	assert.Nil(err)
+	assert.Equal(config.GenesisParticipatesPolicy, participates.PolicyName())
+	accounts, err := participates.GetParticipates()
+	assert.Nil(err)
+	assert.Equal(2, len(accounts))
+	assert.Equal(tools.HexToAddress("343c3310824b7c685133f2bedb2ca4b8b4df633d"), accounts[1].Address)
+	assert.Equal(uint64(1), accounts[1].Extension.Id)
+	assert.Equal("127.0.0.1:30015", accounts[1].Extension.Url)
+}
+
+func TestGalaxyParticipatesConf(t *testing.T) {
+	assert := assert.New(t)
+	conf := galaxyParticipatesConf(participatesConfig.ParticipateConfig{PolicyName: config.GenesisParticipatesPolicy})
+	assert.Equal("solo", conf.PolicyName)
+	conf = galaxyParticipatesConf(participatesConfig.ParticipateConfig{PolicyName: "dpos"})
+	assert.Equal("dpos", conf.PolicyName)
+}
@@ -0,0 +1,218 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/txpool"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadResult report the config keys changed on reload.
+type ReloadResult struct {
+	// keys applied to running node
+	Applied []string
+	// keys which take effect only after node process restarted
+	RestartRequired []string
+	// why some keys in RestartRequired could not be applied to running node, by key
+	Reasons map[string]string
+}
+
+// txpool reads its size and cache time only on creation, and has no way to change them later.
+const txpoolLimitReason = "txpool keeps the limit it was created with, and has no setter for it"
+
+// ReloadConfig read justitia.yaml again and override it with command line args, as NewNode does.
+func ReloadConfig(args config.SysConfig) (config.NodeConfig, error) {
+	conf, err := config.LoadNodeConfig()
+	if nil != err {
+		return conf, err
+	}
+	applyLogArgs(args, conf)
+	return conf, nil
+}
+
+// Reload diff conf against the running config, and apply the changes of log setting, monitor endpoints,
+// txsPerBlock, block interval and persistent peers to running node. Persistent peers are only read by p2p
+// on start, so node is restarted in process to apply them. Other changes are reported in result, and
+// kept out of running config. Txpool slots and cache time are among them, as txpool could not change them.
+func (instance *Node) Reload(conf config.NodeConfig) (*ReloadResult, error) {
+	instance.lock.Lock()
+	if !instance.isRunning {
+		instance.lock.Unlock()
+		log.Warn("node service is not running.")
+		return nil, errors.New("node service is not running")
+	}
+	result := &ReloadResult{Reasons: make(map[string]string)}
+	var logChanged, prometheusChanged, expvarChanged, pprofChanged, pacingChanged bool
+	var peersChanged []string
+	for _, key := range config.DiffNodeConfig(instance.config, conf) {
+		switch {
+		case strings.HasPrefix(key, config.LogSetting+"."):
+			logChanged = true
+		case strings.HasPrefix(key, config.PrometheusSetting+"."):
+			prometheusChanged = true
+		case strings.HasPrefix(key, config.ExpvarSetting+"."):
+			expvarChanged = true
+		case strings.HasPrefix(key, config.PprofSetting+"."):
+			pprofChanged = true
+		case config.MaxTxBlock == key, config.BlockProducedTimeInterval == key:
+			pacingChanged = true
+		case strings.HasSuffix(key, "."+config.P2PPersistendPeers):
+			peersChanged = append(peersChanged, key)
+			continue
+		case config.TxpoolSlots == key, config.TxMaxCacheTime == key:
+			result.RestartRequired = append(result.RestartRequired, key)
+			result.Reasons[key] = txpoolLimitReason
+			continue
+		default:
+			result.RestartRequired = append(result.RestartRequired, key)
+			continue
+		}
+		result.Applied = append(result.Applied, key)
+	}
+	if logChanged {
+		instance.reloadLogger(conf.Logger)
+	}
+	if prometheusChanged {
+		instance.monitors.stopPrometheus()
+		instance.monitors.startPrometheus(conf.PrometheusConf)
+		instance.config.PrometheusConf = conf.PrometheusConf
+	}
+	if expvarChanged {
+		instance.monitors.stopExpvar()
+		instance.monitors.startExpvar(conf.ExpvarConf)
+		instance.config.ExpvarConf = conf.ExpvarConf
+	}
+	if pprofChanged {
+		instance.monitors.stopPprof()
+		instance.monitors.startPprof(conf.PprofConf)
+		instance.config.PprofConf = conf.PprofConf
+	}
+	if pacingChanged {
+		instance.reloadPacing(conf.BlockInterval, conf.TxPoolConf.MaxTrsPerBlock)
+	}
+	restart := instance.reloadPeers(conf, peersChanged, result)
+	for _, key := range result.Applied {
+		config.SetValueSource(instance.config, conf.Sources[key], key)
+	}
+	instance.lock.Unlock()
+
+	if len(result.Applied) > 0 {
+		log.Warn("Config reloaded, applied %v.", result.Applied)
+	}
+	if len(result.RestartRequired) > 0 {
+		log.Warn("Config %v changed, which take effect after node restarted.", result.RestartRequired)
+	}
+	for key, reason := range result.Reasons {
+		log.Warn("Config %s is not applied, as %s.", key, reason)
+	}
+	if restart {
+		if err := instance.Restart(); nil != err {
+			return result, fmt.Errorf("persistent peers changed but not applied, as restart failed: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// reloadLogger replace running log setting, log file is only opened again when its path changed.
+func (instance *Node) reloadLogger(logger log.Config) {
+	running := instance.config.Logger.Appenders[config.FileLogAppender]
+	file := logger.Appenders[config.FileLogAppender]
+	var closing io.Writer
+	if nil != running && running.Enabled && nil != running.Output {
+		closing = running.Output
+	}
+	if nil != file && file.Enabled {
+		if nil != closing && running.LogPath == file.LogPath {
+			file.Output = running.Output
+			closing = nil
+		} else if output, err := openLogFile(file.LogPath); nil != err {
+			log.Error("Open log file %s failed with error %v, file log is disabled.", file.LogPath, err)
+			file.Enabled = false
+		} else {
+			file.Output = output
+		}
+	}
+	instance.config.Logger = logger
+	log.SetGlobalConfig(&instance.config.Logger)
+	if closer, ok := closing.(*os.File); ok {
+		closer.Close()
+	}
+}
+
+func (instance *Node) reloadPacing(interval int64, txsPerBlock uint64) {
+	instance.config.BlockInterval = interval
+	instance.config.TxPoolConf.MaxTrsPerBlock = txsPerBlock
+	if limiter, ok := instance.txpool.(*txsLimiter); ok {
+		limiter.setLimit(txsPerBlock)
+	}
+	if nil != instance.pacer {
+		instance.pacer.setLimits(time.Duration(interval)*time.Millisecond, txsPerBlock)
+	}
+}
+
+// reloadPeers copy changed persistent peers into running config, and return whether node should be restarted
+// to apply them. Peers are reported to require restart if node could not be restarted in process.
+func (instance *Node) reloadPeers(conf config.NodeConfig, keys []string, result *ReloadResult) bool {
+	if 0 == len(keys) {
+		return false
+	}
+	err := instance.restartable()
+	var applied []string
+	for _, key := range keys {
+		name := strings.TrimSuffix(key, "."+config.P2PPersistendPeers)
+		if _, injected := instance.injectedP2Ps[name]; injected || nil != err || nil == conf.P2PConf[name] {
+			result.RestartRequired = append(result.RestartRequired, key)
+			continue
+		}
+		peerConf := *conf.P2PConf[name]
+		if running := instance.config.P2PConf[name]; nil != running {
+			peerConf = *running
+			peerConf.PersistentPeers = conf.P2PConf[name].PersistentPeers
+		}
+		instance.config.P2PConf[name] = &peerConf
+		applied = append(applied, key)
+	}
+	result.Applied = append(result.Applied, applied...)
+	return len(applied) > 0
+}
+
+// txsLimiter cap the transactions taken from txpool for a block, as txpool only reads txsPerBlock on creation.
+// The txpool under it is created with the largest txsPerBlock, so that the cap could be raised on reload.
+type txsLimiter struct {
+	txpool.TxsPool
+	limit uint64
+}
+
+func newTxsLimiter(conf txpool.TxPoolConfig, eventCenter types.EventCenter) *txsLimiter {
+	limit := conf.MaxTrsPerBlock
+	conf.MaxTrsPerBlock = txpool.DefaultTxPoolConfig.MaxTrsPerBlock
+	limiter := &txsLimiter{
+		TxsPool: txpool.NewTxPool(conf, eventCenter),
+	}
+	limiter.setLimit(limit)
+	return limiter
+}
+
+// setLimit change the cap, which is sanitized the same way as txpool does.
+func (limiter *txsLimiter) setLimit(limit uint64) {
+	if limit < 1 || limit > txpool.DefaultTxPoolConfig.MaxTrsPerBlock {
+		limit = txpool.DefaultTxPoolConfig.MaxTrsPerBlock
+	}
+	atomic.StoreUint64(&limiter.limit, limit)
+}
+
+func (limiter *txsLimiter) GetTxs() []*types.Transaction {
+	txs := limiter.TxsPool.GetTxs()
+	if limit := atomic.LoadUint64(&limiter.limit); uint64(len(txs)) > limit {
+		// txs from the same account are in nonce order, so the ones kept are still executable.
+		return txs[:limit]
+	}
+	return txs
+}
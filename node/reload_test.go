@@ -0,0 +1,83 @@
+package node
+
+import (
+	"context"
+	"github.com/DSiSc/craft/monitor"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/config"
+	p2pConf "github.com/DSiSc/p2p/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockTxsPool struct {
+	txs []*types.Transaction
+}
+
+func (pool *mockTxsPool) AddTx(tx *types.Transaction) error {
+	pool.txs = append(pool.txs, tx)
+	return nil
+}
+
+func (pool *mockTxsPool) DelTxs(txs []*types.Transaction) {}
+
+func (pool *mockTxsPool) GetTxs() []*types.Transaction {
+	return pool.txs
+}
+
+func TestTxsLimiter(t *testing.T) {
+	assert := assert.New(t)
+	pool := &mockTxsPool{}
+	for i := 0; i < 5; i++ {
+		pool.AddTx(&types.Transaction{})
+	}
+	limiter := &txsLimiter{TxsPool: pool}
+	limiter.setLimit(3)
+	assert.Len(limiter.GetTxs(), 3)
+	limiter.setLimit(10)
+	assert.Len(limiter.GetTxs(), 5)
+	limiter.setLimit(0)
+	assert.Equal(uint64(20480), limiter.limit)
+}
+
+func TestNode_Reload(t *testing.T) {
+	assert := assert.New(t)
+	node, _ := mockNode(t)
+	_, err := node.Reload(mockNodeConfig())
+	assert.NotNil(err)
+
+	assert.Nil(node.Start())
+	defer node.Stop(context.Background())
+	result, err := node.Reload(mockNodeConfig())
+	assert.Nil(err)
+	assert.Empty(result.Applied)
+	assert.Empty(result.RestartRequired)
+
+	conf := mockNodeConfig()
+	conf.BlockInterval = 500
+	conf.TxPoolConf.MaxTrsPerBlock = 100
+	conf.TxPoolConf.GlobalSlots = 100
+	conf.ApiGatewayAddr = "tcp://127.0.0.1:47768"
+	conf.ExpvarConf = monitor.ExpvarConfig{ExpvarEnabled: true, ExpvarPort: "0", ExpvarPath: "/debug/vars"}
+	conf.P2PConf = map[string]*p2pConf.P2PConfig{
+		config.TxP2P: {PersistentPeers: "tcp://127.0.0.1:46662"},
+	}
+	result, err = node.Reload(conf)
+	assert.Nil(err)
+	assert.Equal([]string{config.MaxTxBlock, config.BlockProducedTimeInterval, config.ExpvarEnabled, config.ExpvarPort, config.ExpvarPath}, result.Applied)
+	assert.Contains(result.RestartRequired, config.ApiGatewayAddr)
+	assert.Contains(result.RestartRequired, config.TxpoolSlots)
+	assert.Equal(map[string]string{config.TxpoolSlots: txpoolLimitReason}, result.Reasons)
+	// injected p2p is not rebuilt by restart.
+	assert.Contains(result.RestartRequired, config.TxP2P+"."+config.P2PPersistendPeers)
+
+	interval, blockSize := node.pacer.limits()
+	assert.Equal(500*time.Millisecond, interval)
+	assert.Equal(uint64(100), blockSize)
+	assert.Equal(uint64(100), node.txpool.(*txsLimiter).limit)
+	assert.NotNil(node.monitors.expvar)
+	assert.Equal(conf.ExpvarConf, node.config.ExpvarConf)
+	assert.Equal("", node.config.ApiGatewayAddr)
+	assert.Nil(node.config.P2PConf)
+}
@@ -0,0 +1,40 @@
+package node
+
+import (
+	"errors"
+	"github.com/DSiSc/blockstore"
+	_ "github.com/DSiSc/repository"
+	"github.com/DSiSc/statedb-NG/ethdb"
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// repository keeps the databases opened by InitRepository in package variables without a way to close them,
+// so they are linked here to release their files when node stops.
+
+//go:linkname repositoryStateDB github.com/DSiSc/repository.stateDiskDB
+var repositoryStateDB ethdb.Database
+
+//go:linkname repositoryBlockStore github.com/DSiSc/repository.globalBlockStore
+var repositoryBlockStore *blockstore.BlockStore
+
+// closeRepository close the state database and block store opened by InitRepository.
+func closeRepository() error {
+	var errs []error
+	if nil != repositoryStateDB {
+		errs = append(errs, repositoryStateDB.Close())
+	}
+	if nil != repositoryBlockStore {
+		// block store hides its database in an unexported field.
+		field := reflect.ValueOf(repositoryBlockStore).Elem().FieldByName("store")
+		if !field.IsValid() {
+			return errors.Join(append(errs, errors.New("database of block store not found"))...)
+		}
+		store := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface()
+		if closer, ok := store.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,30 @@
+package node
+
+import (
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/repository"
+	repositoryConfig "github.com/DSiSc/repository/config"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloseRepository(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "justitia")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	conf := repositoryConfig.RepositoryConfig{
+		PluginName:    repository.PLUGIN_LEVELDB,
+		StateDataPath: filepath.Join(dir, "state"),
+		BlockDataPath: filepath.Join(dir, "block"),
+	}
+	assert.Nil(repository.InitRepository(conf, events.NewEvent()))
+	assert.Nil(closeRepository())
+	// leveldb files could only be opened again once released
+	assert.Nil(repository.InitRepository(conf, events.NewEvent()))
+	assert.Nil(closeRepository())
+	assert.Nil(repository.InitRepository(repositoryConfig.RepositoryConfig{PluginName: repository.PLUGIN_MEMDB}, events.NewEvent()))
+}
@@ -0,0 +1,283 @@
+package node
+
+import (
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"github.com/DSiSc/galaxy/consensus"
+	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
+	"github.com/DSiSc/galaxy/participates"
+	"github.com/DSiSc/galaxy/role"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/validator/tools/account"
+	"sync"
+	"time"
+)
+
+// RoundState is the state of block production round on consensus node.
+type RoundState int
+
+const (
+	RoundIdle       RoundState = iota // main loop not started
+	RoundWaiting                      // waiting for block interval before next round
+	RoundElecting                     // deciding master of the round
+	RoundProducing                    // master is making block
+	RoundProposing                    // block is being agreed by consensus
+	RoundCommitting                   // waiting for the result of the round
+	RoundStopped                      // main loop exited
+	RoundPaused                       // block production paused by admin
+)
+
+var roundStateNames = map[RoundState]string{
+	RoundIdle:       "idle",
+	RoundWaiting:    "waiting",
+	RoundElecting:   "electing",
+	RoundProducing:  "producing",
+	RoundProposing:  "proposing",
+	RoundCommitting: "committing",
+	RoundStopped:    "stopped",
+	RoundPaused:     "paused",
+}
+
+func (state RoundState) String() string {
+	if name, ok := roundStateNames[state]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(state))
+}
+
+// RoundTransition is a state change of the round state machine.
+type RoundTransition struct {
+	Round uint64
+	From  RoundState
+	To    RoundState
+	At    time.Time
+}
+
+// RoundObserver is notified of every round transition. It is called by node main loop synchronously,
+// so it must not block.
+type RoundObserver func(transition RoundTransition)
+
+// RoundStatus is a snapshot of the round state machine and block pacing.
+type RoundStatus struct {
+	Round   uint64
+	State   RoundState
+	Since   time.Time
+	Delay   time.Duration // wait before the current round
+	Average time.Duration // average time from round beginning to block committed
+	Timeout time.Duration // time to wait for the result of current round
+	Pending int64         // estimated transactions in txpool
+}
+
+type roundMachine struct {
+	lock      sync.RWMutex
+	round     uint64
+	state     RoundState
+	since     time.Time
+	begin     time.Time
+	observers []RoundObserver
+}
+
+func newRoundMachine(observers []RoundObserver) *roundMachine {
+	now := time.Now()
+	return &roundMachine{
+		state:     RoundIdle,
+		since:     now,
+		begin:     now,
+		observers: observers,
+	}
+}
+
+func (machine *roundMachine) transition(to RoundState) {
+	machine.lock.Lock()
+	if to == RoundElecting {
+		machine.round++
+		machine.begin = time.Now()
+	}
+	transition := RoundTransition{
+		Round: machine.round,
+		From:  machine.state,
+		To:    to,
+		At:    time.Now(),
+	}
+	machine.state = to
+	machine.since = transition.At
+	machine.lock.Unlock()
+	log.Debug("Round %d transit from %s to %s.", transition.Round, transition.From, transition.To)
+	for _, observer := range machine.observers {
+		observer(transition)
+	}
+}
+
+// elapsed return time passed since current round began.
+func (machine *roundMachine) elapsed() time.Duration {
+	machine.lock.RLock()
+	defer machine.lock.RUnlock()
+	return time.Since(machine.begin)
+}
+
+func (machine *roundMachine) status() (uint64, RoundState, time.Time) {
+	machine.lock.RLock()
+	defer machine.lock.RUnlock()
+	return machine.round, machine.state, machine.since
+}
+
+// productionGate hold new rounds back while block production paused.
+type productionGate struct {
+	lock    sync.Mutex
+	resumed chan struct{}
+}
+
+func newProductionGate() *productionGate {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &productionGate{
+		resumed: resumed,
+	}
+}
+
+func (gate *productionGate) pause() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if !gate.pausedWithoutLock() {
+		gate.resumed = make(chan struct{})
+	}
+}
+
+func (gate *productionGate) resume() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if gate.pausedWithoutLock() {
+		close(gate.resumed)
+	}
+}
+
+func (gate *productionGate) paused() bool {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	return gate.pausedWithoutLock()
+}
+
+func (gate *productionGate) pausedWithoutLock() bool {
+	select {
+	case <-gate.resumed:
+		return false
+	default:
+		return true
+	}
+}
+
+// wait block while production paused, and return false if quit closed meanwhile.
+func (gate *productionGate) wait(quit <-chan struct{}) bool {
+	gate.lock.Lock()
+	resumed := gate.resumed
+	gate.lock.Unlock()
+	select {
+	case <-resumed:
+		return true
+	case <-quit:
+		return false
+	}
+}
+
+// RoundPolicy plug a consensus policy into the round state machine.
+type RoundPolicy interface {
+	// Wait report whether block interval should pass before the round triggered by msg.
+	Wait(msg common.MsgType) bool
+	// Assign return the master and participates of the round triggered by msg.
+	Assign(msg common.MsgType) (account.Account, []account.Account, error)
+}
+
+// roundPolicies create round policy by consensus policy name,
+// policies not listed here elect master through role policy every round.
+var roundPolicies = map[string]func(instance *Node) RoundPolicy{
+	consensusCommon.DbftPolicy: newDbftRound,
+	consensusCommon.FbftPolicy: newFbftRound,
+}
+
+func newRoundPolicy(instance *Node) RoundPolicy {
+	if create, ok := roundPolicies[instance.config.ConsensusConf.PolicyName]; ok {
+		return create(instance)
+	}
+	return newElectionRound(instance)
+}
+
+// electionRound elect master by role policy after block interval.
+type electionRound struct {
+	participates participates.Participates
+	role         role.Role
+}
+
+func newElectionRound(instance *Node) RoundPolicy {
+	return &electionRound{
+		participates: instance.participates,
+		role:         instance.role,
+	}
+}
+
+func (round *electionRound) Wait(msg common.MsgType) bool {
+	return true
+}
+
+func (round *electionRound) Assign(msg common.MsgType) (account.Account, []account.Account, error) {
+	participate, err := round.participates.GetParticipates()
+	if err != nil {
+		log.Error("get participates failed with error %s.", err)
+		return account.Account{}, nil, err
+	}
+	_, master, err := round.role.RoleAssignments(participate)
+	if nil != err {
+		log.Error("Role assignments failed with err %v.", err)
+		return account.Account{}, nil, err
+	}
+	return master, participate, nil
+}
+
+// dbftRound start the round with new master right after view changed, otherwise elect master as usual.
+type dbftRound struct {
+	electionRound
+	consensus consensus.Consensus
+}
+
+func newDbftRound(instance *Node) RoundPolicy {
+	return &dbftRound{
+		electionRound: electionRound{
+			participates: instance.participates,
+			role:         instance.role,
+		},
+		consensus: instance.consensus,
+	}
+}
+
+func (round *dbftRound) Wait(msg common.MsgType) bool {
+	return common.MsgChangeMaster != msg
+}
+
+func (round *dbftRound) Assign(msg common.MsgType) (account.Account, []account.Account, error) {
+	if common.MsgChangeMaster == msg {
+		result := round.consensus.GetConsensusResult()
+		return result.Master, result.Participate, nil
+	}
+	return round.electionRound.Assign(msg)
+}
+
+// fbftRound take master from consensus result, and only wait for block interval after a block committed
+// or view changed, so that a failed round is retried at once.
+type fbftRound struct {
+	consensus consensus.Consensus
+}
+
+func newFbftRound(instance *Node) RoundPolicy {
+	return &fbftRound{
+		consensus: instance.consensus,
+	}
+}
+
+func (round *fbftRound) Wait(msg common.MsgType) bool {
+	return common.MsgBlockCommitSuccess == msg || common.MsgChangeMaster == msg
+}
+
+func (round *fbftRound) Assign(msg common.MsgType) (account.Account, []account.Account, error) {
+	result := round.consensus.GetConsensusResult()
+	log.Debug("get participate %v and master %v.", result.Participate, result.Master.Extension.Id)
+	return result.Master, result.Participate, nil
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"errors"
+	consensusCommon "github.com/DSiSc/galaxy/consensus/common"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/config"
+	"github.com/DSiSc/validator/tools/account"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockRoundPolicy struct {
+	wait bool
+	err  error
+}
+
+func (policy *mockRoundPolicy) Wait(msg common.MsgType) bool {
+	return policy.wait
+}
+
+func (policy *mockRoundPolicy) Assign(msg common.MsgType) (account.Account, []account.Account, error) {
+	return account.Account{}, nil, policy.err
+}
+
+func TestRoundState_String(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("committing", RoundCommitting.String())
+	assert.Equal("unknown(100)", RoundState(100).String())
+}
+
+func TestNewRoundPolicy(t *testing.T) {
+	assert := assert.New(t)
+	node := &Node{}
+	node.config.ConsensusConf.PolicyName = consensusCommon.SoloPolicy
+	assert.IsType(&electionRound{}, newRoundPolicy(node))
+	node.config.ConsensusConf.PolicyName = consensusCommon.DbftPolicy
+	policy := newRoundPolicy(node)
+	assert.IsType(&dbftRound{}, policy)
+	assert.False(policy.Wait(common.MsgChangeMaster))
+	assert.True(policy.Wait(common.MsgBlockVerifyFailed))
+	node.config.ConsensusConf.PolicyName = consensusCommon.FbftPolicy
+	policy = newRoundPolicy(node)
+	assert.IsType(&fbftRound{}, policy)
+	assert.True(policy.Wait(common.MsgBlockCommitSuccess))
+	assert.False(policy.Wait(common.MsgWaitTimeOut))
+}
+
+func TestNode_NextRoundAssignFailed(t *testing.T) {
+	assert := assert.New(t)
+	var transitions []RoundTransition
+	node := &Node{
+		config:      config.NodeConfig{},
+		msgChannel:  make(chan common.MsgType, msgChannelCacheLimit),
+		pacer:       newBlockPacer(0, 10, true),
+		roundPolicy: &mockRoundPolicy{wait: true, err: errors.New("no participates")},
+		round: newRoundMachine([]RoundObserver{func(transition RoundTransition) {
+			transitions = append(transitions, transition)
+		}}),
+		quitChan:   make(chan struct{}),
+		production: newProductionGate(),
+	}
+	node.NextRound(common.MsgBlockCommitSuccess)
+	assert.Len(transitions, 3)
+	assert.Equal(RoundWaiting, transitions[0].To)
+	assert.Equal(RoundElecting, transitions[1].To)
+	assert.Equal(uint64(1), transitions[1].Round)
+	assert.Equal(RoundCommitting, transitions[2].To)
+	select {
+	case msg := <-node.msgChannel:
+		assert.Equal(common.MsgRoundRunFailed, msg)
+	case <-time.After(time.Second):
+		assert.Fail("round failure not notified")
+	}
+	status := node.RoundStatus()
+	assert.Equal(uint64(1), status.Round)
+	assert.Equal(RoundCommitting, status.State)
+
+	// paused before electing
+	node.production.pause()
+	done := make(chan interface{})
+	go func() {
+		node.NextRound(common.MsgBlockCommitSuccess)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(RoundPaused, node.RoundStatus().State)
+	node.production.resume()
+	<-done
+	assert.Equal(uint64(2), node.RoundStatus().Round)
+
+	// stopped while waiting for block interval
+	node.pacer = newBlockPacer(time.Hour, 10, true)
+	close(node.quitChan)
+	node.NextRound(common.MsgBlockCommitSuccess)
+	assert.Equal(RoundWaiting, node.RoundStatus().State)
+}
@@ -0,0 +1,216 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/DSiSc/craft/log"
+	"sync"
+)
+
+// Service is a subsystem which could be plugged into node.
+type Service interface {
+	// Name return the unique name of the service, which is referenced by dependents.
+	Name() string
+	// Start start the service, it will be called after all dependencies started.
+	Start() error
+	// Stop stop the service, it will be called before any dependency stopped.
+	Stop() error
+	// Health return nil if service works well.
+	Health() error
+}
+
+// serviceFunc adapt start, stop and health functions to Service, nil function means nothing to do.
+type serviceFunc struct {
+	name   string
+	start  func() error
+	stop   func() error
+	health func() error
+}
+
+func (s *serviceFunc) Name() string {
+	return s.name
+}
+
+func (s *serviceFunc) Start() error {
+	if nil == s.start {
+		return nil
+	}
+	return s.start()
+}
+
+func (s *serviceFunc) Stop() error {
+	if nil == s.stop {
+		return nil
+	}
+	return s.stop()
+}
+
+func (s *serviceFunc) Health() error {
+	if nil == s.health {
+		return nil
+	}
+	return s.health()
+}
+
+type serviceEntry struct {
+	service      Service
+	dependencies []string
+}
+
+// ServiceRegistry keep services with their dependencies,
+// start them in dependency order and stop them in reverse order.
+type ServiceRegistry struct {
+	lock     sync.Mutex
+	entries  []*serviceEntry
+	services map[string]*serviceEntry
+	started  []Service
+}
+
+// NewServiceRegistry create an empty service registry.
+func NewServiceRegistry() *ServiceRegistry {
+	return &ServiceRegistry{
+		entries:  make([]*serviceEntry, 0),
+		services: make(map[string]*serviceEntry),
+	}
+}
+
+// Register add a service which depends on services with specified names.
+// Dependencies are resolved when starting, so they could be registered later.
+func (registry *ServiceRegistry) Register(service Service, dependencies ...string) error {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	name := service.Name()
+	if _, ok := registry.services[name]; ok {
+		return fmt.Errorf("service %s already registered", name)
+	}
+	if len(registry.started) > 0 {
+		return fmt.Errorf("can not register service %s to running registry", name)
+	}
+	entry := &serviceEntry{
+		service:      service,
+		dependencies: dependencies,
+	}
+	registry.entries = append(registry.entries, entry)
+	registry.services[name] = entry
+	return nil
+}
+
+// Service return the service registered with name, nil if not found.
+func (registry *ServiceRegistry) Service(name string) Service {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if entry, ok := registry.services[name]; ok {
+		return entry.service
+	}
+	return nil
+}
+
+// sortServices sort services so that each one comes after its dependencies,
+// services without order constraints keep the order they are registered.
+func (registry *ServiceRegistry) sortServices() ([]Service, error) {
+	for _, entry := range registry.entries {
+		for _, dependency := range entry.dependencies {
+			if _, ok := registry.services[dependency]; !ok {
+				return nil, fmt.Errorf("service %s depends on unknown service %s", entry.service.Name(), dependency)
+			}
+		}
+	}
+	sorted := make([]Service, 0, len(registry.entries))
+	placed := make(map[string]bool)
+	for len(sorted) < len(registry.entries) {
+		progress := false
+		for _, entry := range registry.entries {
+			if placed[entry.service.Name()] {
+				continue
+			}
+			ready := true
+			for _, dependency := range entry.dependencies {
+				if !placed[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, entry.service)
+				placed[entry.service.Name()] = true
+				progress = true
+			}
+		}
+		if !progress {
+			var pending []string
+			for _, entry := range registry.entries {
+				if !placed[entry.service.Name()] {
+					pending = append(pending, entry.service.Name())
+				}
+			}
+			return nil, fmt.Errorf("circular dependency among services %v", pending)
+		}
+	}
+	return sorted, nil
+}
+
+// Start start all services in dependency order. If any of them failed,
+// services already started will be stopped in reverse order and the error is returned.
+func (registry *ServiceRegistry) Start() error {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if len(registry.started) > 0 {
+		return errors.New("services already started")
+	}
+	services, err := registry.sortServices()
+	if nil != err {
+		log.Error("Sort services failed with error %v.", err)
+		return err
+	}
+	for _, service := range services {
+		if err = service.Start(); nil != err {
+			log.Error("Start %s failed with error %v.", service.Name(), err)
+			err = fmt.Errorf("start %s failed: %v", service.Name(), err)
+			if stopErr := registry.stopStarted(context.Background()); nil != stopErr {
+				return errors.Join(err, stopErr)
+			}
+			return err
+		}
+		log.Info("Start %s success.", service.Name())
+		registry.started = append(registry.started, service)
+	}
+	return nil
+}
+
+// Stop stop all started services in reverse order, and return the errors of all failed ones.
+// Services that have not stopped when ctx is done will be reported as failed.
+func (registry *ServiceRegistry) Stop(ctx context.Context) error {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	return registry.stopStarted(ctx)
+}
+
+func (registry *ServiceRegistry) stopStarted(ctx context.Context) error {
+	var errs []error
+	for i := len(registry.started) - 1; i >= 0; i-- {
+		errs = append(errs, stopStep(ctx, registry.started[i].Name(), registry.started[i].Stop))
+	}
+	registry.started = nil
+	return errors.Join(errs...)
+}
+
+// Health return health state of all registered services, services not started are reported as unhealthy.
+func (registry *ServiceRegistry) Health() map[string]error {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	running := make(map[string]bool)
+	for _, service := range registry.started {
+		running[service.Name()] = true
+	}
+	health := make(map[string]error)
+	for _, entry := range registry.entries {
+		name := entry.service.Name()
+		if !running[name] {
+			health[name] = fmt.Errorf("service %s is not running", name)
+			continue
+		}
+		health[name] = entry.service.Health()
+	}
+	return health
+}
@@ -0,0 +1,95 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	justitiaCommon "github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/p2p"
+	"github.com/DSiSc/syncer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func mockService(name string, events *[]string, startErr error) Service {
+	return &serviceFunc{
+		name: name,
+		start: func() error {
+			*events = append(*events, "start "+name)
+			return startErr
+		},
+		stop: func() error {
+			*events = append(*events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestServiceRegistry_Order(t *testing.T) {
+	assert := assert.New(t)
+	var events []string
+	registry := NewServiceRegistry()
+	assert.Nil(registry.Register(mockService("c", &events, nil), "b", "a"))
+	assert.Nil(registry.Register(mockService("a", &events, nil)))
+	assert.Nil(registry.Register(mockService("b", &events, nil), "a"))
+	assert.Nil(registry.Register(mockService("d", &events, nil)))
+	assert.NotNil(registry.Register(mockService("d", &events, nil)))
+	assert.NotNil(registry.Service("c"))
+	assert.Nil(registry.Service("e"))
+
+	assert.Nil(registry.Start())
+	assert.Equal([]string{"start a", "start b", "start d", "start c"}, events)
+	assert.NotNil(registry.Register(mockService("e", &events, nil)))
+	for name, err := range registry.Health() {
+		assert.Nil(err, name)
+	}
+
+	events = nil
+	assert.Nil(registry.Stop(context.Background()))
+	assert.Equal([]string{"stop c", "stop d", "stop b", "stop a"}, events)
+	assert.NotNil(registry.Health()["a"])
+}
+
+func TestServiceRegistry_BadDependency(t *testing.T) {
+	assert := assert.New(t)
+	var events []string
+	registry := NewServiceRegistry()
+	assert.Nil(registry.Register(mockService("a", &events, nil), "b"))
+	assert.Equal(fmt.Errorf("service a depends on unknown service b"), registry.Start())
+
+	assert.Nil(registry.Register(mockService("b", &events, nil), "a"))
+	assert.Equal(fmt.Errorf("circular dependency among services [a b]"), registry.Start())
+	assert.Nil(events)
+}
+
+func TestServiceRegistry_StartFailed(t *testing.T) {
+	assert := assert.New(t)
+	var events []string
+	registry := NewServiceRegistry()
+	assert.Nil(registry.Register(mockService("a", &events, nil)))
+	assert.Nil(registry.Register(mockService("b", &events, errors.New("mock error")), "a"))
+	assert.Nil(registry.Register(mockService("c", &events, nil), "b"))
+	assert.Equal(fmt.Errorf("start b failed: mock error"), registry.Start())
+	assert.Equal([]string{"start a", "start b", "stop a"}, events)
+}
+
+func TestNode_ServiceStopOrder(t *testing.T) {
+	assert := assert.New(t)
+	node := &Node{
+		blockSyncerP2P: (*p2p.P2P)(nil),
+		blockSyncer:    (*syncer.BlockSyncer)(nil),
+		blockP2P:       (*p2p.P2P)(nil),
+		txP2P:          (*p2p.P2P)(nil),
+	}
+	node.config.NodeType = justitiaCommon.ConsensusNode
+	node.config.ApiGatewayAddr = "tcp://0.0.0.0:47768"
+	assert.Nil(node.registerServices())
+	services, err := node.services.sortServices()
+	assert.Nil(err)
+	stopOrder := make([]string, 0, len(services))
+	for i := len(services) - 1; i >= 0; i-- {
+		stopOrder = append(stopOrder, services[i].Name())
+	}
+	assert.Equal([]string{RpcService, BlockPropagatorService, TxPropagatorService, BlockSyncerP2PService, BlockP2PService,
+		TxP2PService, BlockSyncerService, BlockSwitchService, TxSwitchService, ConsensusService}, stopOrder)
+}
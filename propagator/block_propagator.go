@@ -6,11 +6,19 @@ import (
 	"github.com/DSiSc/craft/types"
 	"github.com/DSiSc/justitia/common"
 	"github.com/DSiSc/p2p"
+	pcommon "github.com/DSiSc/p2p/common"
 	"github.com/DSiSc/p2p/message"
+	"github.com/DSiSc/repository"
 	"sync"
+	"time"
 )
 
-//BlockPropagator block message propagator
+// time waiting for the block requested from a peer, after which it is requested from another peer announcing it
+const blockRequestTimeout = 5 * time.Second
+
+// BlockPropagator block message propagator. Committed blocks are announced to peers by their headers, and peers
+// request the full blocks they lack. Persistent peers, which are the direct links between consensus nodes, get
+// full blocks pushed instead.
 type BlockPropagator struct {
 	p2p         p2p.P2PAPI
 	blockOut    chan<- interface{}
@@ -19,20 +27,48 @@ type BlockPropagator struct {
 	subscribers map[types.EventType]types.Subscriber
 	lock        sync.Mutex
 	isRuning    int32
+	knownBlocks *knownCache
+	// look up local block requested by peers
+	blockByHash func(hash types.Hash) (*types.Block, error)
+	// time waiting for requested block
+	requestTimeout time.Duration
+	scores         *peerScores
+	queue          *inboundQueue
 }
 
 // NewBlockPropagator create a new NewBlockPropagator instance.
 func NewBlockPropagator(p2p p2p.P2PAPI, blockOut chan<- interface{}, eventCenter types.EventCenter) (*BlockPropagator, error) {
+	scores := newPeerScores()
 	return &BlockPropagator{
-		p2p:         p2p,
-		blockOut:    blockOut,
-		quitChan:    make(chan interface{}),
-		eventCenter: eventCenter,
-		subscribers: make(map[types.EventType]types.Subscriber),
-		isRuning:    0,
+		p2p:            p2p,
+		blockOut:       blockOut,
+		quitChan:       make(chan interface{}),
+		eventCenter:    eventCenter,
+		subscribers:    make(map[types.EventType]types.Subscriber),
+		isRuning:       0,
+		knownBlocks:    newKnownCache(maxKnownBlocks),
+		blockByHash:    getBlockByHash,
+		requestTimeout: blockRequestTimeout,
+		scores:         scores,
+		queue:          newInboundQueue(blockPropagatorName, DefaultBlockQueueSize, DropOldest, scores),
 	}, nil
 }
 
+// SetInboundQueue change the capacity and drop policy of the queue of received blocks waiting for block
+// switch, invalid ones are ignored.
+func (bp *BlockPropagator) SetInboundQueue(capacity int, policy string) {
+	bp.queue.configure(capacity, policy)
+}
+
+// getBlockByHash get block from the latest state of chain.
+func getBlockByHash(hash types.Hash) (*types.Block, error) {
+	chain, err := repository.NewLatestStateRepository()
+	if nil != err {
+		return nil, err
+	}
+	return chain.GetBlockByHash(hash)
+}
+
 // BlockEventFunc get a EventFunc that can be bound to event center
 func (bp *BlockPropagator) BlockEventFunc(event interface{}) {
 	switch event.(type) {
@@ -43,12 +79,35 @@ func (bp *BlockPropagator) BlockEventFunc(event interface{}) {
 	}
 }
 
-// broadcast message to p2p network
+// peerRemovedFunc forget the scores of disconnected peers.
+func (bp *BlockPropagator) peerRemovedFunc(event interface{}) {
+	bp.scores.peerRemoved(bp.p2p, event)
+}
+
+// announce block to the peers who do not know it, and push it to persistent ones among them.
 func (bp *BlockPropagator) broadCastBlock(block *types.Block) {
 	bmsg := &message.Block{
 		Block: block,
 	}
-	bp.p2p.BroadCast(bmsg)
+	peers, canList := listPeers(bp.p2p)
+	targets, ok := bp.knownBlocks.sending(common.HeaderHash(block), peers)
+	if !ok {
+		return
+	}
+	if !canList {
+		bp.p2p.BroadCast(bmsg)
+		return
+	}
+	announcement := &message.BlockHeaders{
+		Headers: []*types.Header{block.Header},
+	}
+	for _, peer := range targets {
+		if peer.IsPersistent() {
+			go bp.p2p.SendMsg(peer.GetAddr(), bmsg)
+		} else {
+			go bp.p2p.SendMsg(peer.GetAddr(), announcement)
+		}
+	}
 }
 
 // Start start propagator
@@ -63,7 +122,9 @@ func (bp *BlockPropagator) Start() error {
 
 	bp.subscribers[types.EventBlockCommitted] = bp.eventCenter.Subscribe(types.EventBlockCommitted, bp.BlockEventFunc)
 	bp.subscribers[types.EventBlockWritten] = bp.eventCenter.Subscribe(types.EventBlockWritten, bp.BlockEventFunc)
+	bp.subscribers[types.EventRemovePeer] = bp.eventCenter.Subscribe(types.EventRemovePeer, bp.peerRemovedFunc)
 	go bp.recvHandler()
+	go deliverQueued(bp.queue, bp.blockOut, bp.quitChan)
 	return nil
 }
 
@@ -76,13 +137,14 @@ func (bp *BlockPropagator) Stop() {
 	}
 	bp.isRuning = 0
 	close(bp.quitChan)
+	bp.queue.clear()
 	for eventType, subscriber := range bp.subscribers {
 		delete(bp.subscribers, eventType)
 		bp.eventCenter.UnSubscribe(eventType, subscriber)
 	}
 }
 
-// receive handler will receive block from p2p, and send the block to gossip switch
+// receive handler will receive block from p2p, and queue the block for gossip switch
 func (bp *BlockPropagator) recvHandler() {
 	for {
 		select {
@@ -90,8 +152,19 @@ func (bp *BlockPropagator) recvHandler() {
 			switch msg.Payload.(type) {
 			case *message.Block:
 				bmsg := msg.Payload.(*message.Block)
-				log.Debug("received a block %x", common.HeaderHash(bmsg.Block))
-				bp.blockOut <- bmsg.Block
+				hash := common.HeaderHash(bmsg.Block)
+				if bp.knownBlocks.received(hash, msg.From) {
+					log.Debug("drop block %x, as it has been seen before", hash)
+					bp.scores.add(msg.From, -1)
+					queueDropped.WithLabelValues(blockPropagatorName, dropDuplicate).Inc()
+					continue
+				}
+				log.Debug("received a block %x", hash)
+				bp.enqueue(msg.From, bmsg.Block)
+			case *message.BlockHeaders:
+				bp.fetchAnnouncedBlocks(msg.From, msg.Payload.(*message.BlockHeaders).Headers)
+			case *message.BlockReq:
+				bp.sendRequestedBlock(msg.From, msg.Payload.(*message.BlockReq).HeaderHash)
 			default:
 				log.Error("received an invalid block message, message type: %v", msg.Payload.MsgType())
 			}
@@ -101,3 +174,71 @@ func (bp *BlockPropagator) recvHandler() {
 		}
 	}
 }
+
+// queue block received from peer for gossip switch.
+func (bp *BlockPropagator) enqueue(from *pcommon.NetAddress, block *types.Block) {
+	bp.scores.add(from, 1)
+	if bp.queue.push(&inboundItem{from: from, payload: block}) {
+		log.Warn("block queue is full, dropped a block by its drop policy")
+	}
+}
+
+// request the announced blocks we lack from the peer announcing them.
+func (bp *BlockPropagator) fetchAnnouncedBlocks(from *pcommon.NetAddress, headers []*types.Header) {
+	for _, header := range headers {
+		if nil == header {
+			continue
+		}
+		hash := common.HeaderHash(&types.Block{Header: header})
+		if !bp.knownBlocks.announced(hash, from) {
+			continue
+		}
+		if block, err := bp.blockByHash(hash); nil == err && nil != block {
+			bp.knownBlocks.received(hash, nil)
+			continue
+		}
+		log.Debug("request block %x of height %d announced by %s", hash, header.Height, from.ToString())
+		bp.requestBlock(from, hash)
+	}
+}
+
+// request the full block of hash from peer. Peer lacking the block sends nothing, so the block is requested
+// from another peer announcing it if it doesn't arrive in time.
+func (bp *BlockPropagator) requestBlock(from *pcommon.NetAddress, hash types.Hash) {
+	if err := bp.p2p.SendMsg(from, &message.BlockReq{HeaderHash: hash}); nil != err {
+		log.Error("failed to request block %x from peer %s, as: %v", hash, from.ToString(), err)
+		bp.retryBlockRequest(from, hash)
+		return
+	}
+	time.AfterFunc(bp.requestTimeout, func() {
+		bp.retryBlockRequest(from, hash)
+	})
+}
+
+// request the block of hash from another peer announcing it, as its request to peer from failed or timed out.
+func (bp *BlockPropagator) retryBlockRequest(from *pcommon.NetAddress, hash types.Hash) {
+	select {
+	case <-bp.quitChan:
+		return
+	default:
+	}
+	next, ok := bp.knownBlocks.requestFailed(hash, from)
+	if !ok {
+		return
+	}
+	log.Warn("request block %x from peer %s, as it is not received from %s", hash, next.ToString(), from.ToString())
+	bp.requestBlock(next, hash)
+}
+
+// send the block requested by peer.
+func (bp *BlockPropagator) sendRequestedBlock(from *pcommon.NetAddress, hash types.Hash) {
+	block, err := bp.blockByHash(hash)
+	if nil != err || nil == block {
+		log.Warn("peer %s requested block %x which is not found", from.ToString(), hash)
+		return
+	}
+	bp.knownBlocks.received(hash, from)
+	if err = bp.p2p.SendMsg(from, &message.Block{Block: block}); nil != err {
+		log.Error("failed to send block %x to peer %s, as: %v", hash, from.ToString(), err)
+	}
+}
@@ -0,0 +1,190 @@
+package propagator
+
+import (
+	"container/list"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/p2p"
+	"github.com/DSiSc/p2p/common"
+	"sync"
+)
+
+const (
+	// max number of recently seen transactions remembered by tx propagator
+	maxKnownTxs = 32768
+	// max number of recently seen blocks remembered by block propagator
+	maxKnownBlocks = 1024
+)
+
+// peerLister is implemented by p2p service which could enumerate its peers, so that an item could be sent to
+// the peers who do not know it instead of all peers.
+type peerLister interface {
+	GetPeers() []*p2p.Peer
+}
+
+// listPeers return the peers of p2p service, ok is false if p2p service could not enumerate its peers.
+func listPeers(p2pService p2p.P2PAPI) (peers []*p2p.Peer, ok bool) {
+	lister, ok := p2pService.(peerLister)
+	if !ok {
+		return nil, false
+	}
+	return lister.GetPeers(), true
+}
+
+// knownItem record the peers who already know an item
+type knownItem struct {
+	hash  types.Hash
+	peers map[string]struct{}
+	// whether we have the item, or have only heard of it
+	have bool
+	// whether the item has been requested from a peer announcing it, and the peer requested
+	requested     bool
+	requestedFrom string
+	// peers announcing the item we do not have, in the order they announced it
+	announcers []*common.NetAddress
+	// whether the item has been sent to peers by us
+	sent bool
+}
+
+// knownCache is a bounded LRU of recently seen item hashes, along with the peers known to have each item.
+type knownCache struct {
+	capacity int
+	items    map[types.Hash]*list.Element
+	order    *list.List
+	lock     sync.Mutex
+}
+
+// newKnownCache create a known cache remembering at most capacity items.
+func newKnownCache(capacity int) *knownCache {
+	return &knownCache{
+		capacity: capacity,
+		items:    make(map[types.Hash]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get return the item of hash and mark it as recently used, a new item is added if it is not cached yet.
+// the caller should hold the lock.
+func (cache *knownCache) get(hash types.Hash, peer *common.NetAddress) *knownItem {
+	var item *knownItem
+	if element, ok := cache.items[hash]; ok {
+		cache.order.MoveToFront(element)
+		item = element.Value.(*knownItem)
+	} else {
+		item = &knownItem{
+			hash:  hash,
+			peers: make(map[string]struct{}),
+		}
+		cache.items[hash] = cache.order.PushFront(item)
+		for cache.order.Len() > cache.capacity {
+			oldest := cache.order.Back()
+			cache.order.Remove(oldest)
+			delete(cache.items, oldest.Value.(*knownItem).hash)
+		}
+	}
+	if nil != peer {
+		item.peers[peer.ToString()] = struct{}{}
+	}
+	return item
+}
+
+// received record that peer sent the item of hash to us, and report whether we have had the item before.
+func (cache *knownCache) received(hash types.Hash, peer *common.NetAddress) bool {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	item := cache.get(hash, peer)
+	seen := item.have
+	item.have = true
+	return seen
+}
+
+// announced record that peer has the item of hash, and report whether the item should be requested from it,
+// which is true only for the first announcement of an item we do not have, unless its request failed.
+func (cache *knownCache) announced(hash types.Hash, peer *common.NetAddress) bool {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	item := cache.get(hash, peer)
+	if item.have {
+		return false
+	}
+	if nil != peer && !item.announcedBy(peer) {
+		item.announcers = append(item.announcers, peer)
+	}
+	if item.requested {
+		return false
+	}
+	item.requested = true
+	if nil != peer {
+		item.requestedFrom = peer.ToString()
+	}
+	return true
+}
+
+// requestFailed record that the request of item of hash to peer failed or timed out, and return another peer
+// announcing it to request it from. ok is false if the item needs no request any more, or no other peer
+// announced it, in which case it is requested again on its next announcement.
+func (cache *knownCache) requestFailed(hash types.Hash, peer *common.NetAddress) (next *common.NetAddress, ok bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	element, cached := cache.items[hash]
+	if !cached {
+		return nil, false
+	}
+	item := element.Value.(*knownItem)
+	address := peer.ToString()
+	if item.have || !item.requested || item.requestedFrom != address {
+		return nil, false
+	}
+	for index, announcer := range item.announcers {
+		if announcer.ToString() == address {
+			item.announcers = append(item.announcers[:index], item.announcers[index+1:]...)
+			break
+		}
+	}
+	if 0 == len(item.announcers) {
+		item.requested = false
+		item.requestedFrom = ""
+		return nil, false
+	}
+	next = item.announcers[0]
+	item.requestedFrom = next.ToString()
+	return next, true
+}
+
+// announcedBy report whether peer has announced the item.
+func (item *knownItem) announcedBy(peer *common.NetAddress) bool {
+	address := peer.ToString()
+	for _, announcer := range item.announcers {
+		if announcer.ToString() == address {
+			return true
+		}
+	}
+	return false
+}
+
+// sending mark the item of hash as sent, and return the peers who do not know it yet, which are considered
+// knowing it from now on. ok is false if the item has already been sent.
+func (cache *knownCache) sending(hash types.Hash, peers []*p2p.Peer) (targets []*p2p.Peer, ok bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	item := cache.get(hash, nil)
+	item.have = true
+	if item.sent {
+		return nil, false
+	}
+	item.sent = true
+	for _, peer := range peers {
+		address := peer.GetAddr().ToString()
+		if _, known := item.peers[address]; !known {
+			item.peers[address] = struct{}{}
+			targets = append(targets, peer)
+		}
+	}
+	return targets, true
+}
+
+// size return the number of cached items.
+func (cache *knownCache) size() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.order.Len()
+}
@@ -0,0 +1,270 @@
+package propagator
+
+import (
+	"errors"
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/common"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/p2p"
+	pcommon "github.com/DSiSc/p2p/common"
+	"github.com/DSiSc/p2p/message"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// fakeP2P is a p2p service with fixed peers, which records the messages sent by propagator
+type fakeP2P struct {
+	peers     []*p2p.Peer
+	msgChan   chan *p2p.InternalMsg
+	broadcast chan message.Message
+	sent      chan *p2p.InternalMsg
+}
+
+// newFakeP2P create a fake p2p service with peers listening on ports, negative port means persistent peer.
+func newFakeP2P(ports ...int32) *fakeP2P {
+	fake := &fakeP2P{
+		msgChan:   make(chan *p2p.InternalMsg),
+		broadcast: make(chan message.Message, 8),
+		sent:      make(chan *p2p.InternalMsg, 8),
+	}
+	for _, port := range ports {
+		if port < 0 {
+			fake.peers = append(fake.peers, p2p.NewOutboundPeer(nil, peerAddress(-port), true, nil))
+		} else {
+			fake.peers = append(fake.peers, p2p.NewOutboundPeer(nil, peerAddress(port), false, nil))
+		}
+	}
+	return fake
+}
+
+// expectNothingSent assert no message is sent in a short while.
+func (fake *fakeP2P) expectNothingSent(assert *assert.Assertions) {
+	select {
+	case msg := <-fake.sent:
+		assert.Fail("unexpected message", "%v message sent to %s", msg.Payload.MsgType(), msg.To.ToString())
+	case msg := <-fake.broadcast:
+		assert.Fail("unexpected broadcast", "%v message broadcast", msg.MsgType())
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func peerAddress(port int32) *pcommon.NetAddress {
+	return pcommon.NewNetAddress("tcp", "127.0.0.1", port)
+}
+
+func (fake *fakeP2P) Start() error                                                { return nil }
+func (fake *fakeP2P) Stop()                                                       {}
+func (fake *fakeP2P) BroadCast(msg message.Message)                               { fake.broadcast <- msg }
+func (fake *fakeP2P) Gather(peerFilter p2p.PeerFilter, msg message.Message) error { return nil }
+func (fake *fakeP2P) MessageChan() <-chan *p2p.InternalMsg                        { return fake.msgChan }
+func (fake *fakeP2P) GetPeers() []*p2p.Peer                                       { return fake.peers }
+func (fake *fakeP2P) SendMsg(peerAddr *pcommon.NetAddress, msg message.Message) error {
+	fake.sent <- &p2p.InternalMsg{To: peerAddr, Payload: msg}
+	return nil
+}
+
+func TestKnownCache(t *testing.T) {
+	assert := assert.New(t)
+	cache := newKnownCache(2)
+	assert.False(cache.received(types.Hash{1}, peerAddress(1)))
+	assert.True(cache.received(types.Hash{1}, peerAddress(2)))
+	assert.False(cache.received(types.Hash{2}, nil))
+
+	// the least recently used item is evicted
+	assert.True(cache.received(types.Hash{1}, nil))
+	assert.False(cache.received(types.Hash{3}, nil))
+	assert.Equal(2, cache.size())
+	assert.True(cache.received(types.Hash{1}, nil))
+	assert.False(cache.received(types.Hash{2}, nil))
+
+	// item is sent once, to the peers who do not know it
+	peers := newFakeP2P(1, 2, 3).peers
+	targets, ok := cache.sending(types.Hash{1}, peers)
+	assert.True(ok)
+	assert.Equal([]*p2p.Peer{peers[2]}, targets)
+	_, ok = cache.sending(types.Hash{1}, peers)
+	assert.False(ok)
+	targets, ok = cache.sending(types.Hash{4}, peers)
+	assert.True(ok)
+	assert.Equal(peers, targets)
+
+	// announced item is requested once, unless we have it
+	assert.True(cache.announced(types.Hash{5}, peerAddress(1)))
+	assert.False(cache.announced(types.Hash{5}, peerAddress(2)))
+	assert.False(cache.received(types.Hash{5}, peerAddress(1)))
+	assert.False(cache.announced(types.Hash{4}, peerAddress(1)))
+
+	// failed request goes to the next announcer, and is reset when no announcer is left
+	assert.True(cache.announced(types.Hash{6}, peerAddress(1)))
+	assert.False(cache.announced(types.Hash{6}, peerAddress(2)))
+	_, ok = cache.requestFailed(types.Hash{6}, peerAddress(2))
+	assert.False(ok)
+	next, ok := cache.requestFailed(types.Hash{6}, peerAddress(1))
+	assert.True(ok)
+	assert.Equal(peerAddress(2), next)
+	_, ok = cache.requestFailed(types.Hash{6}, peerAddress(2))
+	assert.False(ok)
+	assert.True(cache.announced(types.Hash{6}, peerAddress(3)))
+	assert.False(cache.received(types.Hash{6}, peerAddress(3)))
+	_, ok = cache.requestFailed(types.Hash{6}, peerAddress(3))
+	assert.False(ok)
+}
+
+func TestTxPropagator_DropSeenTx(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeP2P(1, 2, 3)
+	txOut := make(chan interface{})
+	tp, err := NewTxPropagator(fake, txOut, events.NewEvent())
+	assert.Nil(err)
+	assert.Nil(tp.Start())
+	defer tp.Stop()
+
+	tx := &types.Transaction{Data: types.TxData{AccountNonce: 1}}
+	go func() {
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.Transaction{Tx: tx}}
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(2), Payload: &message.Transaction{Tx: tx}}
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.Transaction{Tx: tx}}
+	}()
+	assert.Equal(tx, <-txOut)
+	select {
+	case <-txOut:
+		assert.Fail("duplicate transaction reached gossip switch")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// tx added to pool is only sent to the peer who does not know it
+	tp.TxEventFunc(tx)
+	tp.TxEventFunc(tx)
+	assert.Equal(peerAddress(3), (<-fake.sent).To)
+	fake.expectNothingSent(assert)
+
+	// local tx is broadcast, and its echo is dropped
+	local := &types.Transaction{Data: types.TxData{AccountNonce: 2}}
+	tp.TxEventFunc(local)
+	assert.Equal(common.TxHash(local), (<-fake.broadcast).MsgId())
+	go func() {
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.Transaction{Tx: local}}
+	}()
+	select {
+	case <-txOut:
+		assert.Fail("echo of local transaction reached gossip switch")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestBlockPropagator_DropSeenBlock(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeP2P(1, 2)
+	blockOut := make(chan interface{})
+	bp, err := NewBlockPropagator(fake, blockOut, events.NewEvent())
+	assert.Nil(err)
+	assert.Nil(bp.Start())
+	defer bp.Stop()
+
+	block := &types.Block{Header: &types.Header{Height: 1}}
+	block.HeaderHash = common.HeaderHash(block)
+	go func() {
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.Block{Block: block}}
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(2), Payload: &message.Block{Block: block}}
+	}()
+	assert.Equal(block, <-blockOut)
+	select {
+	case <-blockOut:
+		assert.Fail("duplicate block reached gossip switch")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// both peers know the block, so committing it sends nothing
+	bp.BlockEventFunc(block)
+	fake.expectNothingSent(assert)
+}
+
+func TestBlockPropagator_AnnounceBlock(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeP2P(1, -2)
+	blockOut := make(chan interface{})
+	bp, err := NewBlockPropagator(fake, blockOut, events.NewEvent())
+	assert.Nil(err)
+	local := &types.Block{Header: &types.Header{Height: 2}}
+	hash := common.HeaderHash(local)
+	bp.blockByHash = func(requested types.Hash) (*types.Block, error) {
+		if hash == requested {
+			return local, nil
+		}
+		return nil, errors.New("block not found")
+	}
+	assert.Nil(bp.Start())
+	defer bp.Stop()
+
+	// committed block is announced to common peer and pushed to persistent peer, once though it is both
+	// committed and written
+	bp.BlockEventFunc(local)
+	bp.BlockEventFunc(local)
+	sent := make(map[string]message.Message)
+	for i := 0; i < 2; i++ {
+		msg := <-fake.sent
+		sent[msg.To.ToString()] = msg.Payload
+	}
+	assert.Equal(&message.BlockHeaders{Headers: []*types.Header{local.Header}}, sent[peerAddress(1).ToString()])
+	assert.Equal(&message.Block{Block: local}, sent[peerAddress(2).ToString()])
+	fake.expectNothingSent(assert)
+
+	// peer request the announced block
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.BlockReq{HeaderHash: hash}}
+	assert.Equal(&p2p.InternalMsg{To: peerAddress(1), Payload: &message.Block{Block: local}}, <-fake.sent)
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.BlockReq{HeaderHash: types.Hash{1}}}
+	fake.expectNothingSent(assert)
+
+	// block announced by peers is requested once, and block we have is not requested
+	remote := &types.Block{Header: &types.Header{Height: 3}}
+	announcement := &message.BlockHeaders{Headers: []*types.Header{remote.Header, local.Header}}
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: announcement}
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(2), Payload: announcement}
+	assert.Equal(&p2p.InternalMsg{To: peerAddress(1), Payload: &message.BlockReq{HeaderHash: common.HeaderHash(remote)}}, <-fake.sent)
+	fake.expectNothingSent(assert)
+
+	// fetched block goes to gossip switch, and is not announced to peers who know it
+	go func() {
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.Block{Block: remote}}
+	}()
+	assert.Equal(remote, <-blockOut)
+	bp.BlockEventFunc(remote)
+	fake.expectNothingSent(assert)
+}
+
+func TestBlockPropagator_RetryBlockRequest(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeP2P(1, 2, 3)
+	blockOut := make(chan interface{})
+	bp, err := NewBlockPropagator(fake, blockOut, events.NewEvent())
+	assert.Nil(err)
+	bp.blockByHash = func(types.Hash) (*types.Block, error) {
+		return nil, errors.New("block not found")
+	}
+	bp.requestTimeout = 300 * time.Millisecond
+	assert.Nil(bp.Start())
+	defer bp.Stop()
+
+	// block not received in time is requested from the other peer announcing it
+	remote := &types.Block{Header: &types.Header{Height: 3}}
+	request := &message.BlockReq{HeaderHash: common.HeaderHash(remote)}
+	announcement := &message.BlockHeaders{Headers: []*types.Header{remote.Header}}
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: announcement}
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(2), Payload: announcement}
+	assert.Equal(&p2p.InternalMsg{To: peerAddress(1), Payload: request}, <-fake.sent)
+	fake.expectNothingSent(assert)
+	assert.Equal(&p2p.InternalMsg{To: peerAddress(2), Payload: request}, <-fake.sent)
+
+	// with no announcer left, block is requested on its next announcement
+	time.Sleep(400 * time.Millisecond)
+	fake.expectNothingSent(assert)
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(3), Payload: announcement}
+	assert.Equal(&p2p.InternalMsg{To: peerAddress(3), Payload: request}, <-fake.sent)
+	go func() {
+		fake.msgChan <- &p2p.InternalMsg{From: peerAddress(3), Payload: &message.Block{Block: remote}}
+	}()
+	assert.Equal(remote, <-blockOut)
+	time.Sleep(400 * time.Millisecond)
+	fake.expectNothingSent(assert)
+}
@@ -0,0 +1,32 @@
+package propagator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// names of propagators in metrics labels
+	blockPropagatorName = "block"
+	txPropagatorName    = "tx"
+	// reason of dropping a received item seen before
+	dropDuplicate = "duplicate"
+)
+
+// metrics of propagators, labeled by propagator name. Dropped items are labeled by the reason, which is either
+// duplicate or the drop policy of inbound queue.
+var (
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "propagator",
+		Name:      "queue_depth",
+		Help:      "Num of received items waiting for gossip switch.",
+	}, []string{"propagator"})
+	queueDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "propagator",
+		Name:      "dropped",
+		Help:      "Accumulated num of received items dropped by propagator.",
+	}, []string{"propagator", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(queueDepth, queueDropped)
+}
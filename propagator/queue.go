@@ -0,0 +1,254 @@
+package propagator
+
+import (
+	"container/list"
+	"github.com/DSiSc/p2p"
+	pcommon "github.com/DSiSc/p2p/common"
+	"sync"
+)
+
+// drop policies of inbound queue, applied when an item arrives at a full queue
+const (
+	// drop the oldest item in queue
+	DropOldest = "oldest"
+	// drop the oldest item from the peer of lowest score, or the arriving one if its peer scores lower
+	DropLowestScore = "lowestScore"
+)
+
+const (
+	// DefaultTxQueueSize is the default number of received transactions waiting for tx switch.
+	DefaultTxQueueSize = 4096
+	// DefaultBlockQueueSize is the default number of received blocks waiting for block switch.
+	DefaultBlockQueueSize = 64
+)
+
+// peerScores score peers by the items they send, new items raise the score and duplicates lower it.
+type peerScores struct {
+	lock   sync.Mutex
+	scores map[string]int64
+}
+
+func newPeerScores() *peerScores {
+	return &peerScores{
+		scores: make(map[string]int64),
+	}
+}
+
+func (scores *peerScores) add(peer *pcommon.NetAddress, delta int64) {
+	if nil == peer {
+		return
+	}
+	scores.lock.Lock()
+	defer scores.lock.Unlock()
+	scores.scores[peer.ToString()] += delta
+}
+
+func (scores *peerScores) score(peer *pcommon.NetAddress) int64 {
+	if nil == peer {
+		return 0
+	}
+	scores.lock.Lock()
+	defer scores.lock.Unlock()
+	return scores.scores[peer.ToString()]
+}
+
+// prune forget the scores of peers not in peers, which have disconnected.
+func (scores *peerScores) prune(peers []*p2p.Peer) {
+	connected := make(map[string]struct{}, len(peers))
+	for _, peer := range peers {
+		connected[peer.GetAddr().ToString()] = struct{}{}
+	}
+	scores.lock.Lock()
+	defer scores.lock.Unlock()
+	for address := range scores.scores {
+		if _, ok := connected[address]; !ok {
+			delete(scores.scores, address)
+		}
+	}
+}
+
+// remove forget the score of peer.
+func (scores *peerScores) remove(peer *pcommon.NetAddress) {
+	scores.lock.Lock()
+	defer scores.lock.Unlock()
+	delete(scores.scores, peer.ToString())
+}
+
+// peerRemoved forget the scores of disconnected peers, those of the peers p2p service lists are kept if it
+// could enumerate its peers, otherwise only the removed one is forgotten.
+func (scores *peerScores) peerRemoved(p2pService p2p.P2PAPI, event interface{}) {
+	if peers, ok := listPeers(p2pService); ok {
+		scores.prune(peers)
+	} else if peer, ok := event.(*pcommon.NetAddress); ok && nil != peer {
+		scores.remove(peer)
+	}
+}
+
+// inboundItem is an item received from peer.
+type inboundItem struct {
+	from    *pcommon.NetAddress
+	payload interface{}
+	// order of item in queue, and the key of its peer
+	seq  uint64
+	peer string
+}
+
+// inboundQueue is a bounded queue of received items waiting for gossip switch, so that a slow switch never
+// stalls reading p2p messages. When it is full, an item is dropped by its policy. Items are also queued by
+// their peers, so that finding the item to drop takes time of the number of peers instead of items.
+type inboundQueue struct {
+	name     string
+	capacity int
+	policy   string
+	scores   *peerScores
+	lock     sync.Mutex
+	items    *list.List
+	// elements of items queued by peer, in the order they arrived
+	byPeer map[string]*list.List
+	seq    uint64
+	ready  chan struct{}
+}
+
+// newInboundQueue create an inbound queue, name is the label of its metrics.
+func newInboundQueue(name string, capacity int, policy string, scores *peerScores) *inboundQueue {
+	queue := &inboundQueue{
+		name:   name,
+		scores: scores,
+		items:  list.New(),
+		byPeer: make(map[string]*list.List),
+		ready:  make(chan struct{}, 1),
+	}
+	queue.configure(capacity, policy)
+	return queue
+}
+
+// configure change the capacity and drop policy, invalid ones are left unchanged.
+func (queue *inboundQueue) configure(capacity int, policy string) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	if capacity > 0 {
+		queue.capacity = capacity
+	}
+	if DropOldest == policy || DropLowestScore == policy {
+		queue.policy = policy
+	}
+}
+
+// push add item to queue without blocking, and report whether an item is dropped.
+func (queue *inboundQueue) push(item *inboundItem) bool {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	dropped := false
+	for queue.items.Len() >= queue.capacity {
+		victim := queue.victim(item)
+		if nil == victim {
+			queueDropped.WithLabelValues(queue.name, queue.policy).Inc()
+			return true
+		}
+		queue.remove(victim)
+		queueDropped.WithLabelValues(queue.name, queue.policy).Inc()
+		dropped = true
+	}
+	queue.seq++
+	item.seq = queue.seq
+	if nil != item.from {
+		item.peer = item.from.ToString()
+	}
+	peerItems, ok := queue.byPeer[item.peer]
+	if !ok {
+		peerItems = list.New()
+		queue.byPeer[item.peer] = peerItems
+	}
+	peerItems.PushBack(queue.items.PushBack(item))
+	queueDepth.WithLabelValues(queue.name).Set(float64(queue.items.Len()))
+	select {
+	case queue.ready <- struct{}{}:
+	default:
+	}
+	return dropped
+}
+
+// victim return the queued item to drop for arriving, or nil if arriving should be dropped itself, which is
+// the oldest item of the peer of lowest score. the caller should hold the lock.
+func (queue *inboundQueue) victim(arriving *inboundItem) *list.Element {
+	if DropLowestScore != queue.policy {
+		return queue.items.Front()
+	}
+	queue.scores.lock.Lock()
+	defer queue.scores.lock.Unlock()
+	var lowest *list.Element
+	var lowestScore int64
+	for peer, peerItems := range queue.byPeer {
+		oldest := peerItems.Front().Value.(*list.Element)
+		score := queue.scores.scores[peer]
+		if nil == lowest || score < lowestScore ||
+			(score == lowestScore && oldest.Value.(*inboundItem).seq < lowest.Value.(*inboundItem).seq) {
+			lowest, lowestScore = oldest, score
+		}
+	}
+	if nil != arriving.from && queue.scores.scores[arriving.from.ToString()] < lowestScore {
+		return nil
+	}
+	return lowest
+}
+
+// remove take element out of queue, which is the oldest item of its peer. the caller should hold the lock.
+func (queue *inboundQueue) remove(element *list.Element) {
+	queue.items.Remove(element)
+	peer := element.Value.(*inboundItem).peer
+	peerItems := queue.byPeer[peer]
+	peerItems.Remove(peerItems.Front())
+	if 0 == peerItems.Len() {
+		delete(queue.byPeer, peer)
+	}
+}
+
+// pop take the oldest item, waiting for one until quit is closed.
+func (queue *inboundQueue) pop(quit <-chan interface{}) (*inboundItem, bool) {
+	for {
+		queue.lock.Lock()
+		if front := queue.items.Front(); nil != front {
+			queue.remove(front)
+			queueDepth.WithLabelValues(queue.name).Set(float64(queue.items.Len()))
+			queue.lock.Unlock()
+			return front.Value.(*inboundItem), true
+		}
+		queue.lock.Unlock()
+		select {
+		case <-queue.ready:
+		case <-quit:
+			return nil, false
+		}
+	}
+}
+
+// size return the number of queued items.
+func (queue *inboundQueue) size() int {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	return queue.items.Len()
+}
+
+// clear drop the items left in queue when propagator stopped.
+func (queue *inboundQueue) clear() {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	queue.items.Init()
+	queue.byPeer = make(map[string]*list.List)
+	queueDepth.WithLabelValues(queue.name).Set(0)
+}
+
+// deliverQueued send queued items to out until quit is closed, which also cancels the pending send.
+func deliverQueued(queue *inboundQueue, out chan<- interface{}, quit <-chan interface{}) {
+	for {
+		item, ok := queue.pop(quit)
+		if !ok {
+			return
+		}
+		select {
+		case out <- item.payload:
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package propagator
+
+import (
+	"github.com/DSiSc/craft/types"
+	"github.com/DSiSc/justitia/tools/events"
+	"github.com/DSiSc/p2p"
+	"github.com/DSiSc/p2p/message"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func metricValue(collector prometheus.Metric) float64 {
+	metric := &dto.Metric{}
+	collector.Write(metric)
+	if nil != metric.Gauge {
+		return metric.Gauge.GetValue()
+	}
+	return metric.Counter.GetValue()
+}
+
+func payloads(queue *inboundQueue) []interface{} {
+	items := make([]interface{}, 0)
+	for element := queue.items.Front(); nil != element; element = element.Next() {
+		items = append(items, element.Value.(*inboundItem).payload)
+	}
+	return items
+}
+
+func TestInboundQueue_DropOldest(t *testing.T) {
+	assert := assert.New(t)
+	queue := newInboundQueue("test_oldest", 2, DropOldest, newPeerScores())
+	assert.False(queue.push(&inboundItem{payload: 1}))
+	assert.False(queue.push(&inboundItem{payload: 2}))
+	assert.True(queue.push(&inboundItem{payload: 3}))
+	assert.Equal([]interface{}{2, 3}, payloads(queue))
+	assert.Equal(float64(2), metricValue(queueDepth.WithLabelValues("test_oldest")))
+	assert.Equal(float64(1), metricValue(queueDropped.WithLabelValues("test_oldest", DropOldest)))
+
+	quit := make(chan interface{})
+	item, ok := queue.pop(quit)
+	assert.True(ok)
+	assert.Equal(2, item.payload)
+	assert.Equal(1, queue.size())
+	queue.clear()
+	assert.Equal(float64(0), metricValue(queueDepth.WithLabelValues("test_oldest")))
+
+	// waiting for item is cancelled by quit
+	close(quit)
+	_, ok = queue.pop(quit)
+	assert.False(ok)
+
+	// invalid capacity and policy are ignored
+	queue.configure(0, "newest")
+	assert.Equal(2, queue.capacity)
+	assert.Equal(DropOldest, queue.policy)
+}
+
+func TestInboundQueue_DropLowestScore(t *testing.T) {
+	assert := assert.New(t)
+	scores := newPeerScores()
+	scores.add(peerAddress(1), 5)
+	scores.add(peerAddress(2), -3)
+	queue := newInboundQueue("test_score", 2, DropLowestScore, scores)
+	queue.push(&inboundItem{from: peerAddress(1), payload: 1})
+	queue.push(&inboundItem{from: peerAddress(2), payload: 2})
+
+	// item of the lowest scored peer is dropped
+	assert.True(queue.push(&inboundItem{from: peerAddress(1), payload: 3}))
+	assert.Equal([]interface{}{1, 3}, payloads(queue))
+	// arriving item is dropped as its peer scores lower than queued ones
+	assert.True(queue.push(&inboundItem{from: peerAddress(2), payload: 4}))
+	assert.Equal([]interface{}{1, 3}, payloads(queue))
+	// the oldest item is dropped among peers of the same score
+	assert.True(queue.push(&inboundItem{from: peerAddress(1), payload: 5}))
+	assert.Equal([]interface{}{3, 5}, payloads(queue))
+	assert.Equal(float64(3), metricValue(queueDropped.WithLabelValues("test_score", DropLowestScore)))
+
+	// items are queued by peer, which is forgotten with its last item
+	assert.Equal(1, len(queue.byPeer))
+	assert.Equal(2, queue.byPeer[peerAddress(1).ToString()].Len())
+	quit := make(chan interface{})
+	for _, expect := range []interface{}{3, 5} {
+		item, ok := queue.pop(quit)
+		assert.True(ok)
+		assert.Equal(expect, item.payload)
+	}
+	assert.Equal(0, len(queue.byPeer))
+}
+
+func TestPeerScores_PeerRemoved(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeP2P(1, 2)
+	scores := newPeerScores()
+	for port := int32(1); port <= 3; port++ {
+		scores.add(peerAddress(port), int64(port))
+	}
+
+	// scores of peers p2p no longer lists are forgotten
+	scores.peerRemoved(fake, peerAddress(3))
+	assert.Equal(map[string]int64{peerAddress(1).ToString(): 1, peerAddress(2).ToString(): 2}, scores.scores)
+
+	// only the removed peer is forgotten if p2p could not enumerate its peers
+	scores.peerRemoved(nil, peerAddress(2))
+	assert.Equal(map[string]int64{peerAddress(1).ToString(): 1}, scores.scores)
+}
+
+func TestTxPropagator_SlowSwitch(t *testing.T) {
+	assert := assert.New(t)
+	fake := newFakeP2P(1)
+	txOut := make(chan interface{})
+	tp, err := NewTxPropagator(fake, txOut, events.NewEvent())
+	assert.Nil(err)
+	tp.SetInboundQueue(2, DropOldest)
+	assert.Nil(tp.Start())
+
+	// switch taking nothing does not stall reading messages from p2p
+	duplicates := metricValue(queueDropped.WithLabelValues(txPropagatorName, dropDuplicate))
+	for nonce := uint64(0); nonce < 4; nonce++ {
+		tx := &types.Transaction{Data: types.TxData{AccountNonce: 100 + nonce}}
+		select {
+		case fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.Transaction{Tx: tx}}:
+		case <-time.After(time.Second):
+			assert.FailNow("propagator stalled reading p2p messages")
+		}
+	}
+	fake.msgChan <- &p2p.InternalMsg{From: peerAddress(1), Payload: &message.Transaction{Tx: &types.Transaction{Data: types.TxData{AccountNonce: 100}}}}
+	assert.Equal(duplicates+1, metricValue(queueDropped.WithLabelValues(txPropagatorName, dropDuplicate)))
+	assert.Equal(int64(3), tp.scores.score(peerAddress(1)))
+
+	// stop cancels the pending send to switch
+	stopped := make(chan struct{})
+	go func() {
+		tp.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		assert.Fail("propagator stop hung on sending to switch")
+	}
+	assert.Equal(0, tp.queue.size())
+}
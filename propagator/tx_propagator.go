@@ -19,10 +19,14 @@ type TxPropagator struct {
 	lock        sync.Mutex
 	eventCenter types.EventCenter
 	subscribers map[types.EventType]types.Subscriber
+	knownTxs    *knownCache
+	scores      *peerScores
+	queue       *inboundQueue
 }
 
 // NewBlockPropagator create a new NewBlockPropagator instance.
 func NewTxPropagator(p2p p2p.P2PAPI, txOut chan<- interface{}, eventCenter types.EventCenter) (*TxPropagator, error) {
+	scores := newPeerScores()
 	return &TxPropagator{
 		p2p:         p2p,
 		txOut:       txOut,
@@ -30,9 +34,18 @@ func NewTxPropagator(p2p p2p.P2PAPI, txOut chan<- interface{}, eventCenter types
 		isRuning:    0,
 		eventCenter: eventCenter,
 		subscribers: make(map[types.EventType]types.Subscriber),
+		knownTxs:    newKnownCache(maxKnownTxs),
+		scores:      scores,
+		queue:       newInboundQueue(txPropagatorName, DefaultTxQueueSize, DropOldest, scores),
 	}, nil
 }
 
+// SetInboundQueue change the capacity and drop policy of the queue of received transactions waiting for tx
+// switch, invalid ones are ignored.
+func (tp *TxPropagator) SetInboundQueue(capacity int, policy string) {
+	tp.queue.configure(capacity, policy)
+}
+
 // BlockEventFunc get a EventFunc that can be bound to event center
 func (tp *TxPropagator) TxEventFunc(event interface{}) {
 	switch event.(type) {
@@ -43,12 +56,28 @@ func (tp *TxPropagator) TxEventFunc(event interface{}) {
 	}
 }
 
-// broadcast tx message to p2p network
+// broadcast tx message to p2p network, except the peers who already know the tx
 func (tp *TxPropagator) broadCastTx(tx *types.Transaction) {
 	tmsg := &message.Transaction{
 		Tx: tx,
 	}
-	tp.p2p.BroadCast(tmsg)
+	peers, canList := listPeers(tp.p2p)
+	targets, ok := tp.knownTxs.sending(common.TxHash(tx), peers)
+	if !ok {
+		return
+	}
+	if !canList || len(targets) == len(peers) {
+		tp.p2p.BroadCast(tmsg)
+		return
+	}
+	for _, peer := range targets {
+		go tp.p2p.SendMsg(peer.GetAddr(), tmsg)
+	}
+}
+
+// peerRemovedFunc forget the scores of disconnected peers.
+func (tp *TxPropagator) peerRemovedFunc(event interface{}) {
+	tp.scores.peerRemoved(tp.p2p, event)
 }
 
 // Start start propagator
@@ -62,8 +91,10 @@ func (tp *TxPropagator) Start() error {
 	tp.isRuning = 1
 
 	tp.subscribers[types.EventAddTxToTxPool] = tp.eventCenter.Subscribe(types.EventAddTxToTxPool, tp.TxEventFunc)
+	tp.subscribers[types.EventRemovePeer] = tp.eventCenter.Subscribe(types.EventRemovePeer, tp.peerRemovedFunc)
 
 	go tp.recvHandler()
+	go deliverQueued(tp.queue, tp.txOut, tp.quitChan)
 	return nil
 }
 
@@ -76,13 +107,14 @@ func (tp *TxPropagator) Stop() {
 	}
 	tp.isRuning = 0
 	close(tp.quitChan)
+	tp.queue.clear()
 	for eventType, subscriber := range tp.subscribers {
 		delete(tp.subscribers, eventType)
 		tp.eventCenter.UnSubscribe(eventType, subscriber)
 	}
 }
 
-// receive handler will receive block from p2p, and send the block to gossip switch
+// receive handler will receive tx from p2p, and queue the tx for gossip switch
 func (tp *TxPropagator) recvHandler() {
 	for {
 		select {
@@ -90,8 +122,18 @@ func (tp *TxPropagator) recvHandler() {
 			switch msg.Payload.(type) {
 			case *message.Transaction:
 				txmsg := msg.Payload.(*message.Transaction)
-				log.Debug("received a transaction %x", common.TxHash(txmsg.Tx))
-				tp.txOut <- txmsg.Tx
+				hash := common.TxHash(txmsg.Tx)
+				if tp.knownTxs.received(hash, msg.From) {
+					log.Debug("drop transaction %x, as it has been seen before", hash)
+					tp.scores.add(msg.From, -1)
+					queueDropped.WithLabelValues(txPropagatorName, dropDuplicate).Inc()
+					continue
+				}
+				log.Debug("received a transaction %x", hash)
+				tp.scores.add(msg.From, 1)
+				if tp.queue.push(&inboundItem{from: msg.From, payload: txmsg.Tx}) {
+					log.Debug("transaction queue is full, dropped a transaction by its drop policy")
+				}
 			default:
 				log.Error("received an invalid transaction message, message type: %v", msg.Payload.MsgType())
 			}
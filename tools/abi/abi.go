@@ -0,0 +1,191 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// word size of abi encoding
+const wordSize = 32
+
+var (
+	intTypeRegexp   = regexp.MustCompile(`^(u?int)([0-9]*)$`)
+	bytesTypeRegexp = regexp.MustCompile(`^bytes([0-9]+)$`)
+	addressRegexp   = regexp.MustCompile(`^(0x|0X)?[0-9a-fA-F]{40}$`)
+)
+
+// Value is an argument of contract call given in json, whose type is a solidity elementary type. Arrays and
+// tuples are not supported.
+type Value struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Encode abi encode values as arguments of a contract call or constructor.
+func Encode(values []Value) ([]byte, error) {
+	heads := make([][]byte, 0, len(values))
+	tails := make([][]byte, 0, len(values))
+	for index, value := range values {
+		word, dynamic, err := encodeValue(value)
+		if nil != err {
+			return nil, fmt.Errorf("argument %d: %v", index, err)
+		}
+		if dynamic {
+			heads = append(heads, nil)
+			tails = append(tails, word)
+		} else {
+			heads = append(heads, word)
+			tails = append(tails, nil)
+		}
+	}
+	encoded := make([]byte, 0)
+	offset := len(values) * wordSize
+	for index, head := range heads {
+		if nil == head {
+			head = leftPad(big.NewInt(int64(offset)).Bytes())
+			offset += len(tails[index])
+		}
+		encoded = append(encoded, head...)
+	}
+	for _, tail := range tails {
+		encoded = append(encoded, tail...)
+	}
+	return encoded, nil
+}
+
+// encodeValue return the encoding of value, and whether it is dynamic and kept in tail.
+func encodeValue(value Value) ([]byte, bool, error) {
+	switch {
+	case "address" == value.Type:
+		var address string
+		if err := json.Unmarshal(value.Value, &address); nil != err || !addressRegexp.MatchString(address) {
+			return nil, false, fmt.Errorf("invalid address %s", value.Value)
+		}
+		raw, _ := hex.DecodeString(address[len(address)-40:])
+		return leftPad(raw), false, nil
+	case "bool" == value.Type:
+		var flag bool
+		if err := json.Unmarshal(value.Value, &flag); nil != err {
+			return nil, false, fmt.Errorf("invalid bool %s", value.Value)
+		}
+		if flag {
+			return leftPad([]byte{1}), false, nil
+		}
+		return leftPad(nil), false, nil
+	case "string" == value.Type:
+		var text string
+		if err := json.Unmarshal(value.Value, &text); nil != err {
+			return nil, false, fmt.Errorf("invalid string %s", value.Value)
+		}
+		return encodeDynamic([]byte(text)), true, nil
+	case "bytes" == value.Type:
+		raw, err := decodeHex(value.Value)
+		if nil != err {
+			return nil, false, err
+		}
+		return encodeDynamic(raw), true, nil
+	case bytesTypeRegexp.MatchString(value.Type):
+		size, _ := strconv.Atoi(bytesTypeRegexp.FindStringSubmatch(value.Type)[1])
+		raw, err := decodeHex(value.Value)
+		if nil != err {
+			return nil, false, err
+		}
+		if size < 1 || size > wordSize || len(raw) > size {
+			return nil, false, fmt.Errorf("%d bytes could not be encoded as %s", len(raw), value.Type)
+		}
+		return rightPad(raw), false, nil
+	case intTypeRegexp.MatchString(value.Type):
+		word, err := encodeInt(value)
+		return word, false, err
+	}
+	return nil, false, fmt.Errorf("unsupported type %s", value.Type)
+}
+
+func encodeInt(value Value) ([]byte, error) {
+	matches := intTypeRegexp.FindStringSubmatch(value.Type)
+	bits := 256
+	if "" != matches[2] {
+		bits, _ = strconv.Atoi(matches[2])
+	}
+	if bits < 8 || bits > 256 || 0 != bits%8 {
+		return nil, fmt.Errorf("unsupported type %s", value.Type)
+	}
+	number, err := decodeInt(value.Value)
+	if nil != err {
+		return nil, err
+	}
+	if "uint" == matches[1] {
+		if number.Sign() < 0 || number.BitLen() > bits {
+			return nil, fmt.Errorf("%v overflows %s", number, value.Type)
+		}
+		return leftPad(number.Bytes()), nil
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	if number.Cmp(limit) >= 0 || number.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, fmt.Errorf("%v overflows %s", number, value.Type)
+	}
+	if number.Sign() >= 0 {
+		return leftPad(number.Bytes()), nil
+	}
+	// two's complement in 256 bits
+	complement := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 256), number)
+	return complement.Bytes(), nil
+}
+
+// decodeInt accept a json number, or a string in decimal or 0x prefixed hex.
+func decodeInt(raw json.RawMessage) (*big.Int, error) {
+	text := strings.TrimSpace(string(raw))
+	base := 10
+	if strings.HasPrefix(text, "\"") {
+		if err := json.Unmarshal(raw, &text); nil != err {
+			return nil, fmt.Errorf("invalid integer %s", raw)
+		}
+		base = 0
+	}
+	number, ok := new(big.Int).SetString(text, base)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %s", raw)
+	}
+	return number, nil
+}
+
+func decodeHex(raw json.RawMessage) ([]byte, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); nil != err {
+		return nil, fmt.Errorf("invalid hex string %s", raw)
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X"))
+	if nil != err {
+		return nil, fmt.Errorf("invalid hex string %s", raw)
+	}
+	return decoded, nil
+}
+
+func encodeDynamic(raw []byte) []byte {
+	encoded := leftPad(big.NewInt(int64(len(raw))).Bytes())
+	for start := 0; start < len(raw); start += wordSize {
+		end := start + wordSize
+		if end > len(raw) {
+			end = len(raw)
+		}
+		encoded = append(encoded, rightPad(raw[start:end])...)
+	}
+	return encoded
+}
+
+func leftPad(raw []byte) []byte {
+	word := make([]byte, wordSize)
+	copy(word[wordSize-len(raw):], raw)
+	return word
+}
+
+func rightPad(raw []byte) []byte {
+	word := make([]byte, wordSize)
+	copy(word, raw)
+	return word
+}
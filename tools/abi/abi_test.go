@@ -0,0 +1,54 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	assert := assert.New(t)
+	var values []Value
+	assert.Nil(json.Unmarshal([]byte(`[
+		{"type": "uint256", "value": 69},
+		{"type": "bool", "value": true},
+		{"type": "string", "value": "dave"},
+		{"type": "address", "value": "0x333c3310824b7c685133f2bedb2ca4b8b4df633d"},
+		{"type": "int8", "value": "-1"},
+		{"type": "bytes3", "value": "0x616263"}
+	]`), &values))
+	encoded, err := Encode(values)
+	assert.Nil(err)
+	assert.Equal(""+
+		"0000000000000000000000000000000000000000000000000000000000000045"+
+		"0000000000000000000000000000000000000000000000000000000000000001"+
+		"00000000000000000000000000000000000000000000000000000000000000c0"+
+		"000000000000000000000000333c3310824b7c685133f2bedb2ca4b8b4df633d"+
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"+
+		"6162630000000000000000000000000000000000000000000000000000000000"+
+		"0000000000000000000000000000000000000000000000000000000000000004"+
+		"6461766500000000000000000000000000000000000000000000000000000000",
+		hex.EncodeToString(encoded))
+
+	encoded, err = Encode(nil)
+	assert.Nil(err)
+	assert.Empty(encoded)
+}
+
+func TestEncode_Invalid(t *testing.T) {
+	assert := assert.New(t)
+	for _, value := range []Value{
+		{Type: "uint8", Value: json.RawMessage(`256`)},
+		{Type: "uint256", Value: json.RawMessage(`-1`)},
+		{Type: "int8", Value: json.RawMessage(`128`)},
+		{Type: "uint7", Value: json.RawMessage(`1`)},
+		{Type: "address", Value: json.RawMessage(`"0x01"`)},
+		{Type: "bytes2", Value: json.RawMessage(`"0x010203"`)},
+		{Type: "uint256[]", Value: json.RawMessage(`[1]`)},
+		{Type: "bool", Value: json.RawMessage(`"yes"`)},
+	} {
+		_, err := Encode([]Value{value})
+		assert.NotNil(err, value.Type)
+	}
+}
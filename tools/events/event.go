@@ -98,6 +98,19 @@ func (e *Event) NotifyAll() (errs []error) {
 	return errs
 }
 
+// Subscriptions return the event funcs subscribed to each event type.
+func (e *Event) Subscriptions() map[types.EventType][]types.EventFunc {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	subscriptions := make(map[types.EventType][]types.EventFunc)
+	for eventType, subs := range e.Subscribers {
+		for _, eventFunc := range subs {
+			subscriptions[eventType] = append(subscriptions[eventType], eventFunc)
+		}
+	}
+	return subscriptions
+}
+
 // unsubscribe all event and subscriber elegant
 func (e *Event) UnSubscribeAll() {
 	e.m.Lock()
@@ -93,3 +93,18 @@ func TestEvent_Notify(t *testing.T) {
 	event.Notify(EventSaveBlock, block)
 	time.Sleep(10 * time.Millisecond)
 }
+
+func TestEvent_Subscriptions(t *testing.T) {
+	assert := assert.New(t)
+	event := NewEvent().(*Event)
+	var subscriber types.EventFunc = func(v interface{}) {}
+	sub := event.Subscribe(types.EventBlockCommitted, subscriber)
+	event.Subscribe(types.EventBlockCommitted, subscriber)
+	event.Subscribe(types.EventAddPeer, subscriber)
+	subscriptions := event.Subscriptions()
+	assert.Len(subscriptions[types.EventBlockCommitted], 2)
+	assert.Len(subscriptions[types.EventAddPeer], 1)
+
+	assert.Nil(event.UnSubscribe(types.EventBlockCommitted, sub))
+	assert.Len(event.Subscriptions()[types.EventBlockCommitted], 1)
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"github.com/DSiSc/craft/types"
+	"sync"
+)
+
+// Scope is an event center sharing the events of its parent, which keeps the subscribers subscribed through it,
+// so that they could be unsubscribed together without touching the ones subscribed to parent by others.
+type Scope struct {
+	types.EventCenter
+	m           sync.Mutex
+	subscribers map[types.Subscriber]types.EventType
+}
+
+func NewScope(parent types.EventCenter) *Scope {
+	return &Scope{
+		EventCenter: parent,
+		subscribers: make(map[types.Subscriber]types.EventType),
+	}
+}
+
+// Subscribe subscribe eventType of parent, and keep the subscriber in scope.
+func (s *Scope) Subscribe(eventType types.EventType, eventFunc types.EventFunc) types.Subscriber {
+	s.m.Lock()
+	defer s.m.Unlock()
+	sub := s.EventCenter.Subscribe(eventType, eventFunc)
+	s.subscribers[sub] = eventType
+	return sub
+}
+
+// UnSubscribe unsubscribe subscriber from parent, and remove it from scope.
+func (s *Scope) UnSubscribe(eventType types.EventType, subscriber types.Subscriber) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	delete(s.subscribers, subscriber)
+	return s.EventCenter.UnSubscribe(eventType, subscriber)
+}
+
+// UnSubscribeAll unsubscribe subscribers in scope only.
+func (s *Scope) UnSubscribeAll() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for sub, eventType := range s.subscribers {
+		s.EventCenter.UnSubscribe(eventType, sub)
+	}
+	s.subscribers = make(map[types.Subscriber]types.EventType)
+}
@@ -0,0 +1,25 @@
+package events
+
+import (
+	"github.com/DSiSc/craft/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	assert := assert.New(t)
+	parent := NewEvent()
+	shared := parent.Subscribe(types.EventBlockCommitted, func(interface{}) {})
+	scope := NewScope(parent)
+	scope.Subscribe(types.EventBlockCommitted, func(interface{}) {})
+	sub := scope.Subscribe(types.EventBlockWritten, func(interface{}) {})
+	assert.Len(parent.(*Event).Subscribers[types.EventBlockCommitted], 2)
+
+	assert.Nil(scope.UnSubscribe(types.EventBlockWritten, sub))
+	assert.Empty(parent.(*Event).Subscribers[types.EventBlockWritten])
+	// subscribers unsubscribed already are not closed again
+	scope.UnSubscribeAll()
+	assert.Len(parent.(*Event).Subscribers[types.EventBlockCommitted], 1)
+	assert.Contains(parent.(*Event).Subscribers[types.EventBlockCommitted], shared)
+	assert.Nil(scope.Notify(types.EventBlockCommitted, nil))
+}
@@ -35,15 +35,17 @@ func (set *SignalSet) handle(sig os.Signal, arg interface{}) (err error) {
 	return fmt.Errorf("no handler available for signal %v", sig)
 }
 
+// CatchSysSignal handle registered signals one by one until process exit, signals without handler
+// keep their default behavior.
 func (set *SignalSet) CatchSysSignal() {
-	for {
-		c := make(chan os.Signal)
-		var sigs []os.Signal
-		for sig := range set.m {
-			sigs = append(sigs, sig)
-		}
-		signal.Notify(c)
-		sig := <-c
+	var sigs []os.Signal
+	for sig := range set.m {
+		sigs = append(sigs, sig)
+	}
+	// signals are not blocked for sending, so channel must be buffered.
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	for sig := range c {
 		err := set.handle(sig, nil)
 		if err != nil {
 			log.Warn("unknown signal received: %v.", sig)